@@ -1,6 +1,6 @@
 #!/usr/bin/env python3
 
-import json, time, requests, threading, os, socketio, grpc, sys
+import json, time, requests, threading, os, socketio, grpc, sys, base64, pyotp, hmac
 
 from concurrent import futures
 
@@ -17,11 +17,19 @@ with open(sys.argv[1], mode='r') as json_file:
   TAU_PWD = data['tauros']['password']
   BASE_URL = data['tauros']['base_api_url']
   WS = data['tauros']['websocket']
-  GRPC_PORT = '2224' # data['tauros']['bal_port']
+  TOTP_SECRET = data['tauros'].get('totp_secret', '')
+  # device identity of the login, the one the service always used unless configured
+  DEVICE_ID = os.environ.get('BAL_DEVICE_ID') or data['tauros'].get('device_id') or 'f8c8a829-c1fa-405f-b9e3-0d50c7d2b9f0'
+  GRPC_PORT = os.environ.get('BAL_PORT') or data['tauros'].get('bal_port') or '2224'
+  # TLS of the grpc server, plain text without a certificate, client certificates required with a CA (mTLS)
+  TLS_CERT = os.environ.get('BAL_TLS_CERT') or data['tauros'].get('bal_tls_cert', '')
+  TLS_KEY = os.environ.get('BAL_TLS_KEY') or data['tauros'].get('bal_tls_key', '')
+  TLS_CA = os.environ.get('BAL_TLS_CA') or data['tauros'].get('bal_tls_ca', '')
+  # requests must have the shared token, the bal_token the bots send, or a client certificate named in AUTH_CLIENTS
+  AUTH_TOKEN = os.environ.get('BAL_AUTH_TOKEN') or data.get('grpc', {}).get('bal_token', '')
+  AUTH_CLIENTS = [c.strip() for c in (os.environ.get('BAL_AUTH_CLIENTS') or data['tauros'].get('bal_auth_clients', '')).split(',') if c.strip()]
 
-print('TAU_TOKEN=',TAU_TOKEN)
 print('TAU_EMAIL=',TAU_EMAIL)
-print('TAU_PWD=',TAU_PWD)
 print('BASE_URL=',BASE_URL)
 print('WS=',WS)
 
@@ -36,12 +44,54 @@ class BalancesServicer(balance_pb2_grpc.BalancesServiceServicer):
     }
     return balance_pb2.Balances(**result)
 
-server = grpc.server(futures.ThreadPoolExecutor(max_workers=10))
+def authorized(context):
+  if AUTH_TOKEN:
+    for key, value in context.invocation_metadata():
+      if key == 'authorization' and hmac.compare_digest(value, 'Bearer ' + AUTH_TOKEN):
+        return True
+  if AUTH_CLIENTS:
+    auth = context.auth_context()
+    names = auth.get('x509_common_name', []) + auth.get('x509_subject_alternative_name', [])
+    if any(n.decode() in AUTH_CLIENTS for n in names):
+      return True
+  return False
+
+class AuthInterceptor(grpc.ServerInterceptor):
+  # rejects the requests without the token or an allowed client certificate
+  def intercept_service(self, continuation, handler_call_details):
+    handler = continuation(handler_call_details)
+    if handler is None or handler.unary_unary is None:
+      return handler
+    method = handler_call_details.method
+    def unary_unary(request, context):
+      if not authorized(context):
+        print('Unauthenticated grpc request', method, 'from', context.peer())
+        context.abort(grpc.StatusCode.UNAUTHENTICATED, 'unauthenticated')
+      return handler.unary_unary(request, context)
+    return grpc.unary_unary_rpc_method_handler(unary_unary,
+      request_deserializer=handler.request_deserializer, response_serializer=handler.response_serializer)
+
+if AUTH_CLIENTS and not TLS_CA:
+  sys.exit('bal_auth_clients needs the client certificates CA bal_tls_ca')
+interceptors = [AuthInterceptor()] if AUTH_TOKEN or AUTH_CLIENTS else []
+server = grpc.server(futures.ThreadPoolExecutor(max_workers=10), interceptors=interceptors)
 
 balance_pb2_grpc.add_BalancesServiceServicer_to_server(BalancesServicer(), server)
 
-print('Starting grpc server. Listening on port ',GRPC_PORT)
-server.add_insecure_port('[::]:'+GRPC_PORT)
+print('Starting grpc server. Listening on port ',GRPC_PORT,'TLS',bool(TLS_CERT),'auth',bool(interceptors))
+if TLS_CERT:
+  with open(TLS_CERT, 'rb') as f:
+    cert = f.read()
+  with open(TLS_KEY, 'rb') as f:
+    key = f.read()
+  ca = None
+  if TLS_CA:
+    with open(TLS_CA, 'rb') as f:
+      ca = f.read()
+  creds = grpc.ssl_server_credentials([(key, cert)], root_certificates=ca, require_client_auth=bool(ca))
+  server.add_secure_port('[::]:'+GRPC_PORT, creds)
+else:
+  server.add_insecure_port('[::]:'+GRPC_PORT)
 server.start()
 
 # first load initial balances
@@ -67,26 +117,50 @@ for w in wallets:
    
 # print(balances)
 
-# get jwt token necessary for socketio
-response = requests.post(
-  url=BASE_URL + 'v2/auth/signin/',
-  headers={'Content-Type': 'application/json'},
-  data=json.dumps({
+# get jwt token necessary for socketio, a new one is requested on every (re)connection
+
+REFRESH_MARGIN = 300 # seconds before the jwt expires to reconnect with a fresh one
+
+def jwt_expiration(token):
+  # expiration of the token, None if it does not expire
+  payload = token.split('.')[1]
+  payload += '=' * (-len(payload) % 4)
+  return json.loads(base64.urlsafe_b64decode(payload)).get('exp')
+
+def login():
+  credentials = {
     'email': TAU_EMAIL,
     'password': TAU_PWD,
     'device_name': "Bot",
-    'unique_device_id': "f8c8a829-c1fa-405f-b9e3-0d50c7d2b9f0",
-  }),
-)
-
-print(response.json()) #todo: process invalid login credencials
-jwtToken = response.json()['payload']['token']
-
-# print(jwtToken)
+    'unique_device_id': DEVICE_ID,
+  }
+  response = requests.post(
+    url=BASE_URL + 'v2/auth/signin/',
+    headers={'Content-Type': 'application/json'},
+    data=json.dumps(credentials),
+  )
+  result = response.json()
+  if not result.get('success'):
+    raise Exception('login failed: %s' % result.get('msg'))
+  payload = result['payload']
+  if payload.get('two_factor'):
+    if not TOTP_SECRET:
+      raise Exception('two factor code required but no totp_secret in credentials file')
+    credentials['code'] = pyotp.TOTP(TOTP_SECRET).now()
+    response = requests.post(
+      url=BASE_URL + 'v2/auth/verify-tfa/',
+      headers={'Content-Type': 'application/json', 'Authorization': 'JWT ' + payload['token']},
+      data=json.dumps(credentials),
+    )
+    result = response.json()
+    if not result.get('success'):
+      raise Exception('two factor login failed: %s' % result.get('msg'))
+    payload = result['payload']
+  return payload['token']
 
 # start socketio connection
 
-sio = socketio.Client(reconnection=True)
+sio = socketio.Client(reconnection=False)
 @sio.event
 def connect():
   print('ws connected!')
@@ -130,10 +204,31 @@ def on_message(data):
 def disconnect():
   print('ws disconnected!')
 
-sio.connect(WS+'?token='+jwtToken)
+def refresh_connection(token):
+  # disconnect before the token expires so the loop below reconnects with a fresh one, tokens
+  # without expiration keep the connection until it drops
+  expiration = jwt_expiration(token)
+  if not expiration:
+    return None
+  # short lived tokens are renewed halfway instead of reconnecting right away
+  remaining = expiration - time.time()
+  wait = remaining - min(REFRESH_MARGIN, remaining / 2)
+  timer = threading.Timer(max(wait, 1), sio.disconnect)
+  timer.daemon = True
+  timer.start()
+  return timer
 
 try:
   while True:
-    time.sleep(86400)
+    try:
+      jwtToken = login()
+      sio.connect(WS+'?token='+jwtToken)
+      timer = refresh_connection(jwtToken)
+      sio.wait()
+      if timer:
+        timer.cancel()
+    except Exception as e:
+      print('ws connection error:', e)
+      time.sleep(10)
 except KeyboardInterrupt:
   server.stop(0)
\ No newline at end of file
@@ -0,0 +1,79 @@
+package balances // balances service, Go version of bal/balances.py for the all in one command
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	pb "git.vmo.mx/Tauros/tradingbot/proto"
+	tau "git.vmo.mx/Tauros/tradingbot/taurosapi"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+)
+
+type grpcServer struct{}
+
+// balances of the Tauros account by lower case coin, nil until the first poll
+var balances struct {
+	sync.RWMutex
+	coins map[string]tau.Balance
+}
+
+// Register - adds the balances service to s
+func Register(s *grpc.Server) {
+	pb.RegisterBalancesServiceServer(s, &grpcServer{})
+}
+
+// Start - polls the balances of the account of the taurosapi token every interval. The polls fail
+// until taurosapi.Init is called, in the all in one command by the bots
+func Start(interval time.Duration) {
+	go func() {
+		for {
+			if err := poll(); err != nil {
+				log.Warnf("Unable to get Tauros balances: %v", err)
+			}
+			time.Sleep(interval)
+		}
+	}()
+}
+
+func poll() error {
+	wallets, err := tau.GetBalances()
+	if err != nil {
+		return fmt.Errorf("poll-> %v", err)
+	}
+	coins := make(map[string]tau.Balance, len(wallets))
+	for _, w := range wallets {
+		coins[strings.ToLower(w.Coin)] = w
+	}
+	balances.Lock()
+	balances.coins = coins
+	balances.Unlock()
+	return nil
+}
+
+func (*grpcServer) GetBalances(ctx context.Context, req *pb.BalancesRequest) (*pb.Balances, error) {
+	m := strings.Split(strings.ToLower(req.Market), "-")
+	if len(m) != 2 {
+		return &pb.Balances{}, fmt.Errorf("GetBalances: invalid market %s", req.Market)
+	}
+	balances.RLock()
+	defer balances.RUnlock()
+	if balances.coins == nil {
+		return &pb.Balances{}, fmt.Errorf("GetBalances: no balances yet")
+	}
+	left, ok := balances.coins[m[0]]
+	if !ok {
+		return &pb.Balances{}, fmt.Errorf("GetBalances: no %s wallet", m[0])
+	}
+	right, ok := balances.coins[m[1]]
+	if !ok {
+		return &pb.Balances{}, fmt.Errorf("GetBalances: no %s wallet", m[1])
+	}
+	return &pb.Balances{
+		Left:  &pb.Balance{Currency: m[0], Available: left.Balances.Available.String(), Frozen: left.Balances.Frozen.String()},
+		Right: &pb.Balance{Currency: m[1], Available: right.Balances.Available.String(), Frozen: right.Balances.Frozen.String()},
+	}, nil
+}
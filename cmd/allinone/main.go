@@ -0,0 +1,95 @@
+package main // allinone - gdax, openxrate and balances services and the bots in one process
+
+import (
+	"context"
+	"flag"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"git.vmo.mx/Tauros/tradingbot/balances"
+	"git.vmo.mx/Tauros/tradingbot/gdax"
+	"git.vmo.mx/Tauros/tradingbot/grpcconf"
+	"git.vmo.mx/Tauros/tradingbot/openxrate"
+	"git.vmo.mx/Tauros/tradingbot/taurosbot"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func main() {
+	venues := flag.String("venues", "coinbase", "comma separated venues whose level2 feeds are ingested: coinbase, kraken, binance")
+	fx := flag.String("fx", "", "comma separated venue=factor, prices of the venue are multiplied by factor, e.g. binance=0.999 for USDT to USD")
+	poll := flag.Duration("balances-poll", 2*time.Second, "interval between Tauros balances requests")
+	listen := flag.String("listen", grpcconf.Env("ALLINONE_LISTEN", ""), "address where the services are also served for other clients like testapi, e.g. :2222, none if empty, env ALLINONE_LISTEN")
+	var tlsConf grpcconf.TLS
+	tlsConf.Flags(flag.CommandLine, "", "ALLINONE")
+	var auth grpcconf.Auth
+	auth.Flags(flag.CommandLine, "", "ALLINONE")
+	withReflection := flag.Bool("reflection", grpcconf.Env("ALLINONE_REFLECTION", "") == "true", "serve the grpc reflection service at -listen, env ALLINONE_REFLECTION=true")
+	flag.Parse()
+	botsFile, credentialsFile := flag.Arg(0), flag.Arg(1)
+
+	if err := gdax.Start(strings.Split(*venues, ","), *fx); err != nil {
+		log.Fatalf("Unable to start the gdax service: %v", err)
+	}
+	if err := openxrate.Start(credentialsFile); err != nil {
+		log.Fatalf("Unable to start the openxrate service: %v", err)
+	}
+	balances.Start(*poll)
+
+	// one grpc server for all the services, the bots reach it through an in-memory listener
+	server := newServer(false)
+	inProcess := bufconn.Listen(1 << 20)
+	go func() {
+		if err := server.Serve(inProcess); err != nil {
+			log.Errorf("In-process grpc server stopped: %v", err)
+		}
+	}()
+	// other clients use their own server, TLS, auth and reflection only apply to it
+	var external *grpc.Server
+	if *listen != "" {
+		opts, err := grpcconf.ServerOptions(tlsConf, auth)
+		if err != nil {
+			log.Fatalf("Invalid TLS or auth configuration: %v", err)
+		}
+		listener, err := net.Listen("tcp", *listen)
+		if err != nil {
+			log.Fatalf("Failed to open listening port on %s, %v", *listen, err)
+		}
+		log.Infof("Serving the gdax, openxrate and balances services at %s (TLS %t, auth %t)", *listen, tlsConf.Enabled(), auth.Enabled())
+		external = newServer(*withReflection, opts...)
+		go func() {
+			if err := external.Serve(listener); err != nil {
+				log.Errorf("grpc server at %s stopped: %v", *listen, err)
+			}
+		}()
+	}
+	taurosbot.Dial = func(addr, token string) (*grpc.ClientConn, error) {
+		log.Infof("Using the in-process services instead of %s", addr)
+		return grpc.Dial("inprocess", grpc.WithInsecure(), grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return inProcess.Dial()
+		}))
+	}
+
+	code := taurosbot.Run(botsFile, credentialsFile)
+	server.Stop()
+	if external != nil {
+		external.Stop()
+	}
+	os.Exit(code)
+}
+
+// newServer - grpc server of the gdax, openxrate and balances services
+func newServer(withReflection bool, opts ...grpc.ServerOption) *grpc.Server {
+	s := grpc.NewServer(opts...)
+	gdax.Register(s)
+	openxrate.Register(s)
+	balances.Register(s)
+	if withReflection {
+		reflection.Register(s)
+	}
+	return s
+}
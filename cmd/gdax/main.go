@@ -0,0 +1,7 @@
+package main // gdax service
+
+import "git.vmo.mx/Tauros/tradingbot/gdax"
+
+func main() {
+	gdax.Main()
+}
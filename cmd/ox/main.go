@@ -0,0 +1,7 @@
+package main // ox - Open Exchange Rate service
+
+import "git.vmo.mx/Tauros/tradingbot/openxrate"
+
+func main() {
+	openxrate.Main()
+}
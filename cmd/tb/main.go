@@ -0,0 +1,7 @@
+package main // tb - Tauros trading bots
+
+import "git.vmo.mx/Tauros/tradingbot/taurosbot"
+
+func main() {
+	taurosbot.Main()
+}
@@ -0,0 +1,153 @@
+// Package exchange defines what the bots need from a venue, so the same pricing engine can make
+// markets on any exchange that has an adapter
+package exchange
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// order states
+const (
+	OrderOpen      = "open"
+	OrderFilled    = "filled"
+	OrderCancelled = "cancelled"
+)
+
+// OrderID - order id as given by the exchange
+type OrderID string
+
+// Order - an order of the user on the exchange, Amount is what is left to fill
+type Order struct {
+	ID     OrderID
+	Market string
+	Side   string
+	Price  decimal.Decimal
+	Amount decimal.Decimal
+	Filled decimal.Decimal
+	State  string
+}
+
+// Balance - balance of a coin
+type Balance struct {
+	Coin      string
+	Available decimal.Decimal
+	Frozen    decimal.Decimal
+}
+
+// Fill - a trade of one of the user orders
+type Fill struct {
+	ID      string
+	OrderID OrderID
+	Market  string
+	Side    string
+	Amount  decimal.Decimal
+	Price   decimal.Decimal
+	Fee     decimal.Decimal
+	FeeCoin string
+	Time    time.Time
+}
+
+// CancelResult - outcome of cancelling one order, Err is nil if it was cancelled
+type CancelResult struct {
+	ID  OrderID
+	Err error
+}
+
+// MarketRules - trading rules of a market
+type MarketRules struct {
+	Market    string
+	TickSize  decimal.Decimal
+	LotSize   decimal.Decimal
+	MinAmount decimal.Decimal
+	MinValue  decimal.Decimal
+	MakerFee  decimal.Decimal
+	TakerFee  decimal.Decimal
+}
+
+// Exchange - operations the bots need from a venue. Markets are named "btc-mxn" style, sides are
+// "buy" and "sell"
+type Exchange interface {
+	// Name - short lower case name of the venue
+	Name() string
+	// PlaceOrder - place a limit order
+	PlaceOrder(market, side string, amount, price decimal.Decimal) (OrderID, error)
+	// CancelOrder - cancel an open order
+	CancelOrder(id OrderID) error
+	// CancelOrders - cancel the open orders of market and side, an empty side cancels both sides.
+	// It keeps going after a failure, the error is only set if the open orders could not be listed
+	CancelOrders(market, side string) ([]CancelResult, error)
+	// OpenOrders - open orders of the user in market
+	OpenOrders(market string) ([]Order, error)
+	// GetOrder - an order of the user, open or closed
+	GetOrder(id OrderID) (Order, error)
+	// Balances - balances of the user by coin
+	Balances() (map[string]Balance, error)
+	// MarketRules - trading rules of market
+	MarketRules(market string) (MarketRules, error)
+	// Fills - stream of the fills of the user orders in market, the channel is closed by Close
+	Fills(market string) (<-chan Fill, error)
+	// Close - stop all fill streams
+	Close() error
+}
+
+// RoundPrice - round price to the market tick, buy prices go down and sell prices go up
+// so rounding never makes the order more aggressive
+func (r MarketRules) RoundPrice(side string, price decimal.Decimal) decimal.Decimal {
+	if r.TickSize.Sign() <= 0 {
+		return price
+	}
+	if side == "sell" {
+		return price.Div(r.TickSize).Ceil().Mul(r.TickSize)
+	}
+	return price.Div(r.TickSize).Floor().Mul(r.TickSize)
+}
+
+// RoundAmount - round amount down to the market lot
+func (r MarketRules) RoundAmount(amount decimal.Decimal) decimal.Decimal {
+	if r.LotSize.Sign() <= 0 {
+		return amount
+	}
+	return amount.Div(r.LotSize).Floor().Mul(r.LotSize)
+}
+
+// MinOrderAmount - smallest amount accepted by the market at the given price
+func (r MarketRules) MinOrderAmount(price decimal.Decimal) decimal.Decimal {
+	min := r.MinAmount
+	if r.MinValue.Sign() > 0 && price.Sign() > 0 {
+		byValue := r.MinValue.Div(price)
+		if r.LotSize.Sign() > 0 {
+			byValue = byValue.Div(r.LotSize).Ceil().Mul(r.LotSize)
+		}
+		min = decimal.Max(min, byValue)
+	}
+	return min
+}
+
+// CheckOrder - returns an error if the exchange would reject an order of this amount and price
+func (r MarketRules) CheckOrder(amount, price decimal.Decimal) error {
+	if amount.LessThan(r.MinAmount) {
+		return fmt.Errorf("amount %s is less than the %s minimum amount %s", amount, r.Market, r.MinAmount)
+	}
+	if value := amount.Mul(price); value.LessThan(r.MinValue) {
+		return fmt.Errorf("value %s is less than the %s minimum value %s", value, r.Market, r.MinValue)
+	}
+	return nil
+}
+
+// CancelErrors - combine the failures of CancelOrders results in one error, nil if all orders were cancelled
+func CancelErrors(results []CancelResult) error {
+	var failed []string
+	for _, r := range results {
+		if r.Err != nil {
+			failed = append(failed, fmt.Sprintf("#%s: %v", r.ID, r.Err))
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return fmt.Errorf("unable to cancel %d of %d orders: %s", len(failed), len(results), strings.Join(failed, "; "))
+}
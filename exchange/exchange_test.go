@@ -0,0 +1,133 @@
+package exchange_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"git.vmo.mx/Tauros/tradingbot/exchange"
+	tau "git.vmo.mx/Tauros/tradingbot/taurosapi"
+	"github.com/shopspring/decimal"
+)
+
+// marketsFixture - Tauros data/listmarkets response with the rules of two markets
+const marketsFixture = `{"success": true, "data": {"markets": [
+	{"name": "XRP-MXN", "left_coin": "XRP", "right_coin": "MXN", "price_decimals": 4, "amount_decimals": 2,
+		"min_amount": "1", "min_value": "10", "maker_fee": "0.0025", "taker_fee": "0.005"},
+	{"name": "ETH-BTC", "left_coin": "ETH", "right_coin": "BTC", "price_decimals": 6, "amount_decimals": 4,
+		"min_amount": "0.001", "min_value": "0.0001", "maker_fee": "0.001", "taker_fee": "0.002"}
+]}}`
+
+// fixtureRules - rules of the markets of marketsFixture, read through taurosapi and the Tauros adapter
+func fixtureRules(t *testing.T) map[string]exchange.MarketRules {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/data/listmarkets/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(marketsFixture))
+	}))
+	defer srv.Close()
+	tau.SetURL(srv.URL)
+	rules := make(map[string]exchange.MarketRules)
+	for _, market := range []string{"xrp-mxn", "eth-btc"} {
+		r, err := exchange.NewTauros().MarketRules(market)
+		if err != nil {
+			t.Fatalf("MarketRules(%s): %v", market, err)
+		}
+		rules[market] = r
+	}
+	return rules
+}
+
+func dec(s string) decimal.Decimal {
+	d, err := decimal.NewFromString(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func TestRoundPrice(t *testing.T) {
+	rules := fixtureRules(t)
+	tests := []struct {
+		market, side, price, want string
+	}{
+		{"xrp-mxn", "buy", "5.12345", "5.1234"},
+		{"xrp-mxn", "sell", "5.12341", "5.1235"},
+		{"xrp-mxn", "buy", "5.1234", "5.1234"},
+		{"xrp-mxn", "sell", "5.1234", "5.1234"},
+		{"xrp-mxn", "buy", "0.00009", "0"},
+		{"eth-btc", "buy", "0.0312345678", "0.031234"},
+		{"eth-btc", "sell", "0.0312340001", "0.031235"},
+		{"", "buy", "5.12345", "5.12345"}, //no tick
+	}
+	for _, tt := range tests {
+		got := rules[tt.market].RoundPrice(tt.side, dec(tt.price))
+		if !got.Equal(dec(tt.want)) {
+			t.Errorf("%s RoundPrice(%s, %s) = %s, want %s", tt.market, tt.side, tt.price, got, tt.want)
+		}
+	}
+}
+
+func TestRoundAmount(t *testing.T) {
+	rules := fixtureRules(t)
+	tests := []struct {
+		market, amount, want string
+	}{
+		{"xrp-mxn", "10.129", "10.12"},
+		{"xrp-mxn", "10.12", "10.12"},
+		{"xrp-mxn", "0.009", "0"},
+		{"eth-btc", "1.23456", "1.2345"},
+		{"", "1.23456", "1.23456"}, //no lot
+	}
+	for _, tt := range tests {
+		got := rules[tt.market].RoundAmount(dec(tt.amount))
+		if !got.Equal(dec(tt.want)) {
+			t.Errorf("%s RoundAmount(%s) = %s, want %s", tt.market, tt.amount, got, tt.want)
+		}
+	}
+}
+
+func TestMinOrderAmount(t *testing.T) {
+	rules := fixtureRules(t)
+	tests := []struct {
+		market, price, want string
+	}{
+		{"xrp-mxn", "20", "1"},        //min value 10 needs 0.5, below the min amount
+		{"xrp-mxn", "5", "2"},         //min value 10 needs 2
+		{"xrp-mxn", "3", "3.34"},      //10/3 rounded up to the lot
+		{"xrp-mxn", "0", "1"},         //no price, only the min amount
+		{"eth-btc", "0.03", "0.0034"}, //0.0001/0.03 = 0.00333 rounded up to the lot
+	}
+	for _, tt := range tests {
+		got := rules[tt.market].MinOrderAmount(dec(tt.price))
+		if !got.Equal(dec(tt.want)) {
+			t.Errorf("%s MinOrderAmount(%s) = %s, want %s", tt.market, tt.price, got, tt.want)
+		}
+	}
+}
+
+func TestCheckOrder(t *testing.T) {
+	rules := fixtureRules(t)
+	tests := []struct {
+		market, amount, price string
+		err                   string //part of the error, empty if accepted
+	}{
+		{"xrp-mxn", "2", "5", ""},
+		{"xrp-mxn", "0.99", "100", "minimum amount 1"},
+		{"xrp-mxn", "1.5", "5", "minimum value 10"},
+		{"eth-btc", "0.001", "0.1", ""},
+		{"eth-btc", "0.001", "0.03", "minimum value 0.0001"},
+	}
+	for _, tt := range tests {
+		err := rules[tt.market].CheckOrder(dec(tt.amount), dec(tt.price))
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("%s CheckOrder(%s, %s) = %v, want it accepted", tt.market, tt.amount, tt.price, err)
+		case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+			t.Errorf("%s CheckOrder(%s, %s) = %v, want %q", tt.market, tt.amount, tt.price, err, tt.err)
+		}
+	}
+}
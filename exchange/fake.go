@@ -0,0 +1,277 @@
+package exchange
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Fake - in memory Exchange for tests and paper trading, orders never fill unless Fill is called
+type Fake struct {
+	mux      sync.Mutex
+	rules    map[string]MarketRules
+	balances map[string]Balance
+	orders   map[OrderID]*Order
+	nextID   int
+	fills    map[string][]chan Fill
+	closed   bool
+	// Err, if set, is returned by every call
+	Err error
+}
+
+// NewFake - create a fake exchange with the markets rules and no balances
+func NewFake(rules ...MarketRules) *Fake {
+	f := &Fake{
+		rules:    make(map[string]MarketRules),
+		balances: make(map[string]Balance),
+		orders:   make(map[OrderID]*Order),
+		fills:    make(map[string][]chan Fill),
+	}
+	for _, r := range rules {
+		f.rules[strings.ToLower(r.Market)] = r
+	}
+	return f
+}
+
+// Name - "fake"
+func (f *Fake) Name() string {
+	return "fake"
+}
+
+// SetBalance - set the available balance of a coin
+func (f *Fake) SetBalance(coin string, available decimal.Decimal) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	b := f.balances[coin]
+	b.Coin = coin
+	b.Available = available
+	f.balances[coin] = b
+}
+
+// PlaceOrder - place a limit order, it is checked against the market rules and balances
+func (f *Fake) PlaceOrder(market, side string, amount, price decimal.Decimal) (OrderID, error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	if f.Err != nil {
+		return "", f.Err
+	}
+	r, ok := f.rules[market]
+	if !ok {
+		return "", fmt.Errorf("unknown market %s", market)
+	}
+	if side != "buy" && side != "sell" {
+		return "", fmt.Errorf("invalid side %s", side)
+	}
+	if err := r.CheckOrder(amount, price); err != nil {
+		return "", err
+	}
+	coin, cost := f.coins(market, side, amount, price)
+	b := f.balances[coin]
+	if b.Available.LessThan(cost) {
+		return "", fmt.Errorf("insufficient %s balance", coin)
+	}
+	b.Available = b.Available.Sub(cost)
+	b.Frozen = b.Frozen.Add(cost)
+	f.balances[coin] = b
+	f.nextID++
+	id := OrderID(strconv.Itoa(f.nextID))
+	f.orders[id] = &Order{ID: id, Market: market, Side: side, Price: price, Amount: amount, State: OrderOpen}
+	return id, nil
+}
+
+// CancelOrder - cancel an open order
+func (f *Fake) CancelOrder(id OrderID) error {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	if f.Err != nil {
+		return f.Err
+	}
+	return f.cancel(id)
+}
+
+// CancelOrders - cancel the open orders of market and side
+func (f *Fake) CancelOrders(market, side string) ([]CancelResult, error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	var results []CancelResult
+	for id, o := range f.orders {
+		if o.State == OrderOpen && o.Market == market && (side == "" || o.Side == side) {
+			results = append(results, CancelResult{ID: id, Err: f.cancel(id)})
+		}
+	}
+	return results, nil
+}
+
+// OpenOrders - open orders of the user in market
+func (f *Fake) OpenOrders(market string) ([]Order, error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	var open []Order
+	for _, o := range f.orders {
+		if o.State == OrderOpen && o.Market == market {
+			open = append(open, *o)
+		}
+	}
+	return open, nil
+}
+
+// GetOrder - an order of the user, open or closed
+func (f *Fake) GetOrder(id OrderID) (Order, error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	if f.Err != nil {
+		return Order{}, f.Err
+	}
+	o, ok := f.orders[id]
+	if !ok {
+		return Order{}, fmt.Errorf("order %s not found", id)
+	}
+	return *o, nil
+}
+
+// Balances - balances of the user by coin
+func (f *Fake) Balances() (map[string]Balance, error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	b := make(map[string]Balance, len(f.balances))
+	for coin, balance := range f.balances {
+		b[coin] = balance
+	}
+	return b, nil
+}
+
+// MarketRules - trading rules of market
+func (f *Fake) MarketRules(market string) (MarketRules, error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	if f.Err != nil {
+		return MarketRules{}, f.Err
+	}
+	r, ok := f.rules[market]
+	if !ok {
+		return MarketRules{}, fmt.Errorf("unknown market %s", market)
+	}
+	return r, nil
+}
+
+// Fills - stream of the fills made with Fill in market
+func (f *Fake) Fills(market string) (<-chan Fill, error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	c := make(chan Fill, 100)
+	if f.closed {
+		close(c)
+		return c, nil
+	}
+	f.fills[market] = append(f.fills[market], c)
+	return c, nil
+}
+
+// Close - close all fill streams
+func (f *Fake) Close() error {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	if !f.closed {
+		f.closed = true
+		for _, streams := range f.fills {
+			for _, c := range streams {
+				close(c)
+			}
+		}
+	}
+	return nil
+}
+
+// Fill - fill amount of an open order at its price, as if another user took it
+func (f *Fake) Fill(id OrderID, amount decimal.Decimal) error {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	o, ok := f.orders[id]
+	if !ok || o.State != OrderOpen {
+		return fmt.Errorf("order %s is not open", id)
+	}
+	amount = decimal.Min(amount, o.Amount)
+	left, right := coinsOf(o.Market)
+	value := amount.Mul(o.Price)
+	if o.Side == "buy" {
+		f.move(right, value.Neg(), left, amount)
+	} else {
+		f.move(left, amount.Neg(), right, value)
+	}
+	o.Amount = o.Amount.Sub(amount)
+	o.Filled = o.Filled.Add(amount)
+	if o.Amount.Sign() <= 0 {
+		o.State = OrderFilled
+	}
+	fill := Fill{
+		ID:      fmt.Sprintf("%s-%s", id, o.Filled),
+		OrderID: id,
+		Market:  o.Market,
+		Side:    o.Side,
+		Amount:  amount,
+		Price:   o.Price,
+		Time:    time.Now(),
+	}
+	for _, c := range f.fills[o.Market] {
+		select {
+		case c <- fill:
+		default: // nobody is reading, drop it like a lost websocket message
+		}
+	}
+	return nil
+}
+
+// move takes the frozen balance of an order from one coin and credits the other
+func (f *Fake) move(from string, frozen decimal.Decimal, to string, amount decimal.Decimal) {
+	b := f.balances[from]
+	b.Frozen = b.Frozen.Add(frozen)
+	f.balances[from] = b
+	b = f.balances[to]
+	b.Coin = to
+	b.Available = b.Available.Add(amount)
+	f.balances[to] = b
+}
+
+func (f *Fake) cancel(id OrderID) error {
+	o, ok := f.orders[id]
+	if !ok || o.State != OrderOpen {
+		return fmt.Errorf("order %s is not open", id)
+	}
+	coin, frozen := f.coins(o.Market, o.Side, o.Amount, o.Price)
+	b := f.balances[coin]
+	b.Frozen = b.Frozen.Sub(frozen)
+	b.Available = b.Available.Add(frozen)
+	f.balances[coin] = b
+	o.State = OrderCancelled
+	return nil
+}
+
+// coins returns the coin and amount an order freezes
+func (f *Fake) coins(market, side string, amount, price decimal.Decimal) (string, decimal.Decimal) {
+	left, right := coinsOf(market)
+	if side == "buy" {
+		return right, amount.Mul(price)
+	}
+	return left, amount
+}
+
+func coinsOf(market string) (left, right string) {
+	m := strings.SplitN(market, "-", 2)
+	if len(m) < 2 {
+		return market, ""
+	}
+	return m[0], m[1]
+}
@@ -0,0 +1,241 @@
+package exchange
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tau "git.vmo.mx/Tauros/tradingbot/taurosapi"
+	"github.com/shopspring/decimal"
+	log "github.com/sirupsen/logrus"
+)
+
+// Tauros - Exchange adapter of Tauros, taurosapi must be initialised before using it.
+// Fills are polled from the trades history every PollInterval.
+type Tauros struct {
+	PollInterval time.Duration
+	mux          sync.Mutex
+	quit         chan bool
+	streams      sync.WaitGroup
+}
+
+// NewTauros - create the Tauros adapter
+func NewTauros() *Tauros {
+	return &Tauros{PollInterval: 5 * time.Second, quit: make(chan bool)}
+}
+
+// Name - "tauros"
+func (t *Tauros) Name() string {
+	return "tauros"
+}
+
+// PlaceOrder - place a limit order
+func (t *Tauros) PlaceOrder(market, side string, amount, price decimal.Decimal) (OrderID, error) {
+	id, err := tau.PlaceOrder(tau.Message{
+		Market: market,
+		Amount: &amount,
+		Side:   side,
+		Type:   "limit",
+		Price:  &price,
+	})
+	if err != nil {
+		return "", err
+	}
+	return tauOrderID(id), nil
+}
+
+// CancelOrder - cancel an open order
+func (t *Tauros) CancelOrder(id OrderID) error {
+	tauID, err := parseTauOrderID(id)
+	if err != nil {
+		return err
+	}
+	return tau.CloseOrder(tauID)
+}
+
+// CancelOrders - cancel the open orders of market and side
+func (t *Tauros) CancelOrders(market, side string) ([]CancelResult, error) {
+	if market == "" {
+		return nil, fmt.Errorf("CancelOrders-> market is required")
+	}
+	results, err := tau.CancelOrders(tau.CancelFilter{Market: market, Side: side})
+	if err != nil {
+		return nil, err
+	}
+	return TauCancelResults(results), nil
+}
+
+// TauCancelResults - convert the results of the taurosapi CancelOrders
+func TauCancelResults(results []tau.CancelResult) []CancelResult {
+	r := make([]CancelResult, len(results))
+	for i, result := range results {
+		r[i] = CancelResult{ID: tauOrderID(result.ID), Err: result.Err}
+	}
+	return r
+}
+
+// OpenOrders - open orders of the user in market
+func (t *Tauros) OpenOrders(market string) ([]Order, error) {
+	orders, err := tau.GetOpenOrders()
+	if err != nil {
+		return nil, err
+	}
+	var open []Order
+	for _, o := range orders {
+		if strings.EqualFold(o.Market, market) {
+			open = append(open, Order{
+				ID:     tauOrderID(o.ID),
+				Market: strings.ToLower(o.Market),
+				Side:   strings.ToLower(o.Side),
+				Price:  o.Price,
+				Amount: o.Amount,
+				Filled: o.Filled,
+				State:  OrderOpen,
+			})
+		}
+	}
+	return open, nil
+}
+
+// GetOrder - an order of the user, open or closed
+func (t *Tauros) GetOrder(id OrderID) (Order, error) {
+	tauID, err := parseTauOrderID(id)
+	if err != nil {
+		return Order{}, err
+	}
+	o, err := tau.GetOrder(tauID)
+	if err != nil {
+		return Order{}, err
+	}
+	return Order{
+		ID:     id,
+		Market: strings.ToLower(o.Market),
+		Side:   strings.ToLower(o.Side),
+		Price:  o.Price,
+		Amount: o.Amount,
+		Filled: o.Filled,
+		State:  o.State(),
+	}, nil
+}
+
+// Balances - balances of the user by coin
+func (t *Tauros) Balances() (map[string]Balance, error) {
+	balances, err := tau.GetBalances()
+	if err != nil {
+		return nil, err
+	}
+	b := make(map[string]Balance, len(balances))
+	for _, w := range balances {
+		coin := strings.ToLower(w.Coin)
+		b[coin] = Balance{Coin: coin, Available: w.Balances.Available, Frozen: w.Balances.Frozen}
+	}
+	return b, nil
+}
+
+// MarketRules - trading rules of market
+func (t *Tauros) MarketRules(market string) (MarketRules, error) {
+	m, err := tau.GetMarket(market)
+	if err != nil {
+		return MarketRules{}, err
+	}
+	return TauRules(m), nil
+}
+
+// TauRules - trading rules of a Tauros market
+func TauRules(m tau.Market) MarketRules {
+	return MarketRules{
+		Market:    strings.ToLower(m.Name),
+		TickSize:  m.TickSize(),
+		LotSize:   m.LotSize(),
+		MinAmount: m.MinAmount,
+		MinValue:  m.MinValue,
+		MakerFee:  m.MakerFee,
+		TakerFee:  m.TakerFee,
+	}
+}
+
+// Fills - stream of the fills of the user orders in market, polled from the trades history
+func (t *Tauros) Fills(market string) (<-chan Fill, error) {
+	fills := make(chan Fill, 100)
+	t.streams.Add(1)
+	go func() {
+		defer t.streams.Done()
+		defer close(fills)
+		start := time.Now()
+		since := start
+		seen := make(map[int64]bool)
+		ticker := time.NewTicker(t.PollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				trades, err := tau.GetTrades(market, since.Add(-time.Minute)) // overlap for clock skew, seen avoids duplicates
+				if err != nil {
+					log.Warnf("tauros: unable to get %s trades: %v", market, err)
+					continue
+				}
+				// only the trades of the last query can show up again
+				current := make(map[int64]bool, len(trades))
+				for _, tr := range trades {
+					current[tr.ID] = true
+					if seen[tr.ID] {
+						continue
+					}
+					created, _ := time.Parse(time.RFC3339, tr.CreatedAt)
+					if created.Before(start.Truncate(time.Second)) {
+						continue
+					}
+					if created.After(since) {
+						since = created
+					}
+					select {
+					case fills <- Fill{
+						ID:      strconv.FormatInt(tr.ID, 10),
+						OrderID: tauOrderID(tr.OrderID),
+						Market:  strings.ToLower(tr.Market),
+						Side:    strings.ToLower(tr.Side),
+						Amount:  tr.Amount,
+						Price:   tr.Price,
+						Fee:     tr.Fee,
+						FeeCoin: tr.FeeCoin,
+						Time:    created,
+					}:
+					case <-t.quit:
+						return
+					}
+				}
+				seen = current
+			case <-t.quit:
+				return
+			}
+		}
+	}()
+	return fills, nil
+}
+
+// Close - stop all fill streams
+func (t *Tauros) Close() error {
+	t.mux.Lock()
+	select {
+	case <-t.quit:
+	default:
+		close(t.quit)
+	}
+	t.mux.Unlock()
+	t.streams.Wait()
+	return nil
+}
+
+func tauOrderID(id int64) OrderID {
+	return OrderID(strconv.FormatInt(id, 10))
+}
+
+func parseTauOrderID(id OrderID) (int64, error) {
+	tauID, err := strconv.ParseInt(string(id), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid Tauros order id %s", id)
+	}
+	return tauID, nil
+}
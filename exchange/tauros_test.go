@@ -0,0 +1,129 @@
+package exchange_test
+
+import (
+	"errors"
+	"testing"
+
+	"git.vmo.mx/Tauros/tradingbot/exchange"
+	tau "git.vmo.mx/Tauros/tradingbot/taurosapi"
+	"git.vmo.mx/Tauros/tradingbot/taurosapi/taurosfake"
+	"github.com/shopspring/decimal"
+)
+
+func startTauros() (*taurosfake.Server, *exchange.Tauros) {
+	fake := taurosfake.New("test-token")
+	tau.Init(false, "test-token")
+	tau.SetURL(fake.URL)
+	fake.SetBalance("btc", decimal.New(2, 0))
+	fake.SetBalance("mxn", decimal.New(100000, 0))
+	return fake, exchange.NewTauros()
+}
+
+func TestTaurosOrders(t *testing.T) {
+	fake, venue := startTauros()
+	defer fake.Close()
+
+	id, err := venue.PlaceOrder("btc-mxn", "buy", decimal.New(1, 0), decimal.New(2000, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	open, err := venue.OpenOrders("btc-mxn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(open) != 1 || open[0].ID != id || open[0].Side != "buy" || open[0].State != exchange.OrderOpen {
+		t.Fatalf("OpenOrders = %+v, want #%s", open, id)
+	}
+	if open, _ := venue.OpenOrders("eth-mxn"); len(open) != 0 {
+		t.Errorf("eth-mxn OpenOrders = %+v, want none", open)
+	}
+	balances, err := venue.Balances()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := balances["mxn"]; !b.Available.Equal(decimal.New(98000, 0)) || !b.Frozen.Equal(decimal.New(2000, 0)) {
+		t.Errorf("mxn balance = %+v, want 98000 available and 2000 frozen", b)
+	}
+
+	if err := venue.CancelOrder(id); err != nil {
+		t.Fatal(err)
+	}
+	o, err := venue.GetOrder(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.State != exchange.OrderCancelled {
+		t.Errorf("cancelled order state = %s", o.State)
+	}
+	if err := venue.CancelOrder("not-a-number"); err == nil {
+		t.Errorf("CancelOrder of a bad id did not fail")
+	}
+}
+
+func TestTaurosCancelOrders(t *testing.T) {
+	fake, venue := startTauros()
+	defer fake.Close()
+	var ids []exchange.OrderID
+	for _, o := range []struct {
+		side  string
+		price int64
+	}{{"buy", 2000}, {"sell", 2100}, {"sell", 2200}} {
+		id, err := venue.PlaceOrder("btc-mxn", o.side, decimal.New(1, -1), decimal.New(o.price, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+
+	results, err := venue.CancelOrders("btc-mxn", "sell")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || exchange.CancelErrors(results) != nil {
+		t.Errorf("CancelOrders sell = %+v, want the 2 sells cancelled", results)
+	}
+	if open, _ := venue.OpenOrders("btc-mxn"); len(open) != 1 || open[0].ID != ids[0] {
+		t.Errorf("open orders = %+v, want only the buy #%s", open, ids[0])
+	}
+	if _, err := venue.CancelOrders("", ""); err == nil {
+		t.Errorf("CancelOrders without market did not fail")
+	}
+
+	fake.FailNext(1, 503)
+	results, err = venue.CancelOrders("btc-mxn", "")
+	if err == nil {
+		t.Fatalf("CancelOrders with the open orders failing = %+v, want an error", results)
+	}
+}
+
+func TestTaurosMarketRules(t *testing.T) {
+	fake, venue := startTauros()
+	defer fake.Close()
+	rules, err := venue.MarketRules("BTC-MXN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := exchange.TauRules(taurosfake.DefaultMarkets[0])
+	if rules.Market != "btc-mxn" || !rules.TickSize.Equal(decimal.New(1, -2)) || !rules.LotSize.Equal(decimal.New(1, -8)) ||
+		!rules.MinAmount.Equal(want.MinAmount) || !rules.MinValue.Equal(want.MinValue) || !rules.MakerFee.Equal(want.MakerFee) {
+		t.Errorf("MarketRules = %+v, want %+v", rules, want)
+	}
+	if _, err := venue.MarketRules("xyz-mxn"); err == nil {
+		t.Errorf("MarketRules of an unknown market did not fail")
+	}
+}
+
+func TestTauCancelResults(t *testing.T) {
+	failed := errors.New("order not open")
+	results := exchange.TauCancelResults([]tau.CancelResult{{ID: 1}, {ID: 2, Err: failed}})
+	if len(results) != 2 || results[0].ID != "1" || results[0].Err != nil || results[1].ID != "2" || results[1].Err != failed {
+		t.Fatalf("TauCancelResults = %+v", results)
+	}
+	err := exchange.CancelErrors(results)
+	if err == nil || err.Error() != "unable to cancel 1 of 2 orders: #2: order not open" {
+		t.Errorf("CancelErrors = %v", err)
+	}
+	if err := exchange.CancelErrors(results[:1]); err != nil {
+		t.Errorf("CancelErrors without failures = %v", err)
+	}
+}
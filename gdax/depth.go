@@ -0,0 +1,175 @@
+package gdax // gdax service
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/shopspring/decimal"
+)
+
+// depth modes of GetSpreadPrice
+const (
+	modeLevel    = "level"    //price of the level where the amounts add up to depth
+	modeVWAP     = "vwap"     //average price to fill depth
+	modeNotional = "notional" //price of the level where the values add up to depth
+	modeSlippage = "slippage" //price depth bps away from the best price
+)
+
+// depthQuote - result of walking a side of a book
+type depthQuote struct {
+	Price  decimal.Decimal
+	Size   decimal.Decimal //amount filled at Price
+	Levels int             //price levels used
+}
+
+// levelsDone tells levels to stop before next, given the amount and value of the levels so far
+type levelsDone func(next obItem, amount, value decimal.Decimal) bool
+
+// levels - best price levels of a side times fx, until done
+func (o orderbook) levels(side string, fx decimal.Decimal, done levelsDone) []obItem {
+	var items []obItem
+	var amount, value decimal.Decimal
+	add := func(item obItem) bool {
+		item.Price = item.Price.Mul(fx)
+		if done(item, amount, value) {
+			return false
+		}
+		items = append(items, item)
+		amount = amount.Add(item.Amount)
+		value = value.Add(item.Amount.Mul(item.Price))
+		return true
+	}
+	o.RLock()
+	defer o.RUnlock()
+	if side == "buy" {
+		for iter := o.Bids.Min(); !iter.Limit() && add(iter.Item().(obItem)); iter = iter.Next() {
+		}
+		return items
+	}
+	for iter := o.Asks.Max(); !iter.NegativeLimit() && add(iter.Item().(obItem)); iter = iter.Prev() {
+	}
+	return items
+}
+
+// venueLevels - best levels of a side of one venue or of all venues summed by price, fx normalised.
+// Each venue is only walked until done, deeper levels of a venue cannot be reached in the
+// consolidated book before its own levels are done
+func venueLevels(venue, market, side string, done levelsDone) ([]obItem, error) {
+	venues := []string{venue}
+	if venue == consolidated {
+		venues = nil
+		for v := range venueBooks {
+			venues = append(venues, v)
+		}
+	}
+	byPrice := make(map[string]obItem)
+	for _, v := range venues {
+		book, err := getBook(v, market)
+		if err != nil {
+			return nil, err
+		}
+		for _, l := range book.levels(side, venueFX[v], done) {
+			item := byPrice[l.Price.String()]
+			item.Price = l.Price
+			item.Amount = item.Amount.Add(l.Amount)
+			byPrice[l.Price.String()] = item
+		}
+	}
+	items := make([]obItem, 0, len(byPrice))
+	for _, item := range byPrice {
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if side == "buy" {
+			return items[i].Price.GreaterThan(items[j].Price)
+		}
+		return items[i].Price.LessThan(items[j].Price)
+	})
+	return items, nil
+}
+
+// venueDepthPrice - walk a side of the book of a venue according to mode. If the book is not deep
+// enough the quote is the whole side, with a Size smaller than depth
+func venueDepthPrice(venue, market, side, mode string, depth decimal.Decimal) (q depthQuote, err error) {
+	if depth.Sign() <= 0 {
+		return q, fmt.Errorf("depth must be positive: %s", depth)
+	}
+	var done levelsDone
+	switch mode {
+	case "", modeLevel, modeVWAP:
+		done = func(next obItem, amount, value decimal.Decimal) bool { return amount.GreaterThanOrEqual(depth) }
+	case modeNotional:
+		done = func(next obItem, amount, value decimal.Decimal) bool { return value.GreaterThanOrEqual(depth) }
+	case modeSlippage:
+		best, err := venueLevels(venue, market, side, firstLevel)
+		if err != nil {
+			return q, err
+		}
+		if len(best) == 0 {
+			return q, fmt.Errorf("%s %s book has no %s orders", venue, market, side)
+		}
+		bps := depth.Div(decimal.New(10000, 0))
+		if side == "buy" {
+			q.Price = best[0].Price.Mul(decimal.New(1, 0).Sub(bps))
+			done = func(next obItem, amount, value decimal.Decimal) bool { return next.Price.LessThan(q.Price) }
+		} else {
+			q.Price = best[0].Price.Mul(decimal.New(1, 0).Add(bps))
+			done = func(next obItem, amount, value decimal.Decimal) bool { return next.Price.GreaterThan(q.Price) }
+		}
+	default:
+		return q, fmt.Errorf("Invalid mode %s, must be level, vwap, notional or slippage", mode)
+	}
+	items, err := venueLevels(venue, market, side, done)
+	if err != nil {
+		return q, err
+	}
+	if len(items) == 0 {
+		return q, fmt.Errorf("%s %s book has no %s orders", venue, market, side)
+	}
+	var value decimal.Decimal
+	for _, item := range items {
+		if mode != modeSlippage && done(item, q.Size, value) {
+			break
+		}
+		take := item.Amount
+		switch mode {
+		case modeVWAP:
+			take = decimal.Min(take, depth.Sub(q.Size))
+		case modeNotional:
+			take = decimal.Min(take, depth.Sub(value).Div(item.Price))
+		case "", modeLevel:
+			take = decimal.Min(take, depth.Sub(q.Size))
+		}
+		q.Size = q.Size.Add(take)
+		value = value.Add(take.Mul(item.Price))
+		q.Levels++
+		if mode != modeSlippage {
+			q.Price = item.Price
+		}
+	}
+	if mode == modeVWAP {
+		q.Price = value.Div(q.Size)
+	}
+	return q, nil
+}
+
+// firstLevel stops levels after the best level
+func firstLevel(next obItem, amount, value decimal.Decimal) bool {
+	return amount.Sign() > 0
+}
+
+// venueTicker - best bid and ask of one venue or of all venues, fx normalised
+func venueTicker(venue, market string) (maxBid, minAsk decimal.Decimal, err error) {
+	bids, err := venueLevels(venue, market, "buy", firstLevel)
+	if err != nil {
+		return maxBid, minAsk, err
+	}
+	asks, err := venueLevels(venue, market, "sell", firstLevel)
+	if err != nil {
+		return maxBid, minAsk, err
+	}
+	if len(bids) == 0 || len(asks) == 0 {
+		return maxBid, minAsk, fmt.Errorf("%s %s book is empty", venue, market)
+	}
+	return bids[0].Price, asks[0].Price, nil
+}
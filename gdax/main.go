@@ -1,8 +1,9 @@
-package main // gdax service
+package gdax // gdax service
 
 import (
 	"context"
 	"errors"
+	"flag"
 	"fmt"
 	"net"
 	"os"
@@ -11,6 +12,7 @@ import (
 	"sync"
 	"syscall"
 
+	"git.vmo.mx/Tauros/tradingbot/grpcconf"
 	pb "git.vmo.mx/Tauros/tradingbot/proto"
 	ws "github.com/gorilla/websocket"
 	gdax "github.com/preichenberger/go-coinbasepro/v2"
@@ -36,7 +38,7 @@ type orderbook struct {
 	Bids *rbtree.Tree
 }
 
-var orderbooks map[string]orderbook
+var orderbooks map[string]*orderbook
 
 var markets = []string{"BTC-USD", "LTC-USD", "BCH-USD", "XLM-USD", "DASH-USD"}  //todo get from command line args
 
@@ -74,30 +76,6 @@ func (o orderbook) updateAsk(price string, amount string) {
 	o.Asks.Insert(ask)
 }
 
-func (o orderbook) getBidSpreadPrice(amount decimal.Decimal) float64 {
-	o.RLock()
-	defer o.RUnlock()
-	iter := o.Bids.Min()
-	bidAmount := iter.Item().(obItem).Amount
-	for ; !iter.Limit() && bidAmount.LessThan(amount); iter = iter.Next() {
-		bidAmount = bidAmount.Add(iter.Item().(obItem).Amount)
-	}
-	r, _ := iter.Item().(obItem).Price.Float64()
-	return r
-}
-
-func (o orderbook) getAskSpreadPrice(amount decimal.Decimal) float64 {
-	o.RLock()
-	defer o.RUnlock()
-	iter := o.Asks.Max()
-	askAmount := iter.Item().(obItem).Amount
-	for ; !iter.NegativeLimit() && askAmount.LessThan(amount); iter = iter.Prev() {
-		askAmount = askAmount.Add(iter.Item().(obItem).Amount)
-	}
-	r, _ := iter.Item().(obItem).Price.Float64()
-	return r
-}
-
 func (o orderbook) getTicker() (maxBid decimal.Decimal, minAsk decimal.Decimal) {
 	var mb, ma decimal.Decimal
 	o.RLock()
@@ -113,11 +91,26 @@ func (o orderbook) getTicker() (maxBid decimal.Decimal, minAsk decimal.Decimal)
 
 //todo: create a markets available grpc service.
 
-func (o orderbook) reset() {
-	o.Lock() 
+func (o *orderbook) reset() {
+	o.Lock()
+	o.clear()
+	o.Unlock()
+}
+
+// clear empties the book, must be called with the book locked
+func (o *orderbook) clear() {
 	o.Asks = rbtree.NewTree(compareOrders)
 	o.Bids = rbtree.NewTree(compareOrders)
-	o.Unlock()
+}
+
+// truncate keeps only the best depth levels of each side, must be called with the book locked
+func (o *orderbook) truncate(depth int) {
+	for o.Bids.Len() > depth {
+		o.Bids.DeleteWithIterator(o.Bids.Max())
+	}
+	for o.Asks.Len() > depth {
+		o.Asks.DeleteWithIterator(o.Asks.Min())
+	}
 }
 
 type grpcServer struct{}
@@ -128,38 +121,50 @@ func (*grpcServer) GetSpreadPrice(ctx context.Context, req *pb.SpreadPriceReques
 	//log.Infof("Get Spread Price request invoked with %+v", req)
 	market := strings.ToUpper(req.Market)
 	side := strings.ToLower(req.Side)
-	if _, ok := orderbooks[req.Market]; !ok {
+	venue := strings.ToLower(req.Venue)
+	if _, ok := orderbooks[market]; !ok {
 		return &pb.SpreadPrice{}, errors.New("Invalid market specified in call to GetSpreadPrice service: " + market)
 	}
 	depth, err := decimal.NewFromString(req.Depth)
 	if err != nil {
 		return &pb.SpreadPrice{}, err
 	}
-	if side == "buy" {
-		return &pb.SpreadPrice{
-			Market: market,
-			Price:  fmt.Sprintf("%f", orderbooks[market].getBidSpreadPrice(depth)),
-		}, nil
+	if side != "buy" && side != "sell" {
+		return &pb.SpreadPrice{}, errors.New("Invalid SpreadPriceRequest side, must be 'buy' or 'sell' side: " + side)
+	}
+	if venue == "" {
+		venue = "coinbase"
 	}
-	if side == "sell" {
-		return &pb.SpreadPrice{
-			Market: market,
-			Price:  fmt.Sprintf("%f", orderbooks[market].getAskSpreadPrice(depth)),
-		}, nil
+	q, err := venueDepthPrice(venue, market, side, strings.ToLower(req.Mode), depth)
+	if err != nil {
+		return &pb.SpreadPrice{}, err
 	}
-	return &pb.SpreadPrice{}, errors.New("Invalid SpreadPriceRequest side, must be 'buy' or 'sell' side: " + side)
+	return &pb.SpreadPrice{
+		Market: market,
+		Price:  q.Price.String(),
+		Size:   q.Size.String(),
+		Levels: int32(q.Levels),
+	}, nil
 }
 
 func (*grpcServer) GetTicker(ctx context.Context, req *pb.TickerRequest) (*pb.Ticker, error) {
 	//log.Infof("Get Ticker request invoked with %+v", req)
 	market := strings.ToUpper(req.Market)
+	venue := strings.ToLower(req.Venue)
 	if _, ok := orderbooks[market]; !ok {
 		return &pb.Ticker{}, errors.New("Invalid market specified in call to GetTicker grpc")
 	}
-	maxBid, minAsk := orderbooks[req.Market].getTicker()
-	log.Infof("Ticker maxBid=%s minAsk=%s", maxBid.String(), minAsk.String())
+	if venue == "" {
+		venue = "coinbase"
+	}
+	maxBid, minAsk, err := venueTicker(venue, market)
+	if err != nil {
+		return &pb.Ticker{}, err
+	}
+	log.Infof("Ticker %s maxBid=%s minAsk=%s", venue, maxBid.String(), minAsk.String())
 	if maxBid.GreaterThanOrEqual(minAsk) {
-		log.Fatal("GetTicker: maxBid cannot be greater or equal to minAsk")
+		// venues of the consolidated book can cross each other
+		return &pb.Ticker{}, fmt.Errorf("GetTicker: %s maxBid %s cannot be greater or equal to minAsk %s", venue, maxBid, minAsk)
 	}
 	return &pb.Ticker{
 		Market: req.Market,
@@ -168,20 +173,32 @@ func (*grpcServer) GetTicker(ctx context.Context, req *pb.TickerRequest) (*pb.Ti
 	}, nil
 }
 
-func startGrpcServer(port string) {
+// Register - adds the ticker, spread price and trades services to s
+func Register(s *grpc.Server) {
+	pb.RegisterTickerServiceServer(s, &grpcServer{})
+	pb.RegisterSpreadPriceServiceServer(s, &grpcServer{})
+	pb.RegisterTradesServiceServer(s, &grpcServer{})
+}
+
+func startGrpcServer(addr string, tlsConf grpcconf.TLS, auth grpcconf.Auth, withReflection bool) {
 	log.Info("Starting grpc server..")
-	listener, err := net.Listen("tcp", ":"+port)
+	opts, err := grpcconf.ServerOptions(tlsConf, auth)
+	if err != nil {
+		log.Fatalf("Invalid TLS or auth configuration: %v", err)
+	}
+	listener, err := net.Listen("tcp", addr)
 	if err != nil {
-		log.Fatalf("Failed to open listening port on %s, %v", port, err)
+		log.Fatalf("Failed to open listening port on %s, %v", addr, err)
+	}
+	gdaxGrpcServer = grpc.NewServer(opts...)
+	Register(gdaxGrpcServer)
+	if withReflection {
+		reflection.Register(gdaxGrpcServer)
 	}
-	gdaxGrpcServer = grpc.NewServer()
-	pb.RegisterTickerServiceServer(gdaxGrpcServer, &grpcServer{})
-	pb.RegisterSpreadPriceServiceServer(gdaxGrpcServer, &grpcServer{})
-	reflection.Register(gdaxGrpcServer)
-	log.Infof("Done. Waiting for grpc requests at port %s...",port)
+	log.Infof("Done. Waiting for grpc requests at %s (TLS %t, auth %t, reflection %t)...", addr, tlsConf.Enabled(), auth.Enabled(), withReflection)
 	err = gdaxGrpcServer.Serve(listener)
 	if err != nil {
-		log.Fatalf("Unable to start listening for grpc on port %s: %v", port, err)
+		log.Fatalf("Unable to start listening for grpc on %s: %v", addr, err)
 	}
 }
 
@@ -236,26 +253,45 @@ func (f *logFormatter) Format(entry *log.Entry) ([]byte, error) {
 	return []byte(fmt.Sprintf("%s %s %s\n", f.LevelDesc[entry.Level], timestamp, entry.Message)), nil
 }
 
-func main() {
+// Start - ingests the level2 feeds of venues, fx are the venue=factor price multipliers. coinbase
+// is required
+func Start(venues []string, fx string) error {
+	if err := parseFX(fx); err != nil {
+		return fmt.Errorf("Start-> bad fx: %v", err)
+	}
+	orderbooks = newBooks()
+	tapes = newTapes()
+	if err := startVenues(venues); err != nil {
+		return fmt.Errorf("Start-> %v", err)
+	}
+	if _, ok := venueBooks["coinbase"]; !ok {
+		return errors.New("Start-> coinbase venue is required")
+	}
+	return nil
+}
+
+// Main runs the gdax service until SIGINT or SIGTERM
+func Main() {
 
 	logFormatter := new(logFormatter)
 	logFormatter.TimestampFormat = "2006-01-02 15:04:05"
 	logFormatter.LevelDesc = []string{"PANIC", "FATAL", "ERROR", "WARNI", "INFOR", "DEBUG","TRACE"}
 	log.SetFormatter(logFormatter)
 
-	orderbooks = make(map[string]orderbook)
-	for _, m := range markets {
-		orderbooks[m] = orderbook{
-			RWMutex: &sync.RWMutex{},
-			Bids:    rbtree.NewTree(compareOrders),
-			Asks:    rbtree.NewTree(compareOrders),
-		}
+	venues := flag.String("venues", "coinbase", "comma separated venues whose level2 feeds are ingested: coinbase, kraken, binance")
+	fx := flag.String("fx", "", "comma separated venue=factor, prices of the venue are multiplied by factor, e.g. binance=0.999 for USDT to USD")
+	listen := flag.String("listen", grpcconf.Env("GDAX_LISTEN", ":2222"), "address of the grpc server, env GDAX_LISTEN")
+	var tlsConf grpcconf.TLS
+	tlsConf.Flags(flag.CommandLine, "", "GDAX")
+	var auth grpcconf.Auth
+	auth.Flags(flag.CommandLine, "", "GDAX")
+	withReflection := flag.Bool("reflection", grpcconf.Env("GDAX_REFLECTION", "") == "true", "serve the grpc reflection service, env GDAX_REFLECTION=true")
+	flag.Parse()
+	if err := Start(strings.Split(*venues, ","), *fx); err != nil {
+		log.Fatalf("Unable to start the gdax service: %v", err)
 	}
 
-	gdaxSubscribe()
-
-	go readGdax()
-	go startGrpcServer("2222") //todo port in parameter
+	go startGrpcServer(*listen, tlsConf, auth, *withReflection)
 	c := make(chan os.Signal, 2)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 	<-c
@@ -305,6 +341,7 @@ func readGdax() {
 		if message.Type == "match" {
 			message.Price = fixPrice(message.Price)
 			//	log.Infof("match===: %s %4s p: %7s a: %12s", market, message.Side, message.Price, message.Size)
+			addMatch(market, int64(message.TradeID), message.Price, message.Size, message.Side, message.Time.Time())
 		}
 	}
 }
@@ -0,0 +1,250 @@
+package gdax // gdax service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	pb "git.vmo.mx/Tauros/tradingbot/proto"
+	"github.com/shopspring/decimal"
+	log "github.com/sirupsen/logrus"
+)
+
+// trade - a Coinbase match, Side is the taker side
+type trade struct {
+	ID    int64
+	Price decimal.Decimal
+	Size  decimal.Decimal
+	Side  string
+	Time  time.Time
+}
+
+// candle - OHLCV of the trades of an interval starting at Time
+type candle struct {
+	Time   time.Time
+	Open   decimal.Decimal
+	High   decimal.Decimal
+	Low    decimal.Decimal
+	Close  decimal.Decimal
+	Volume decimal.Decimal
+	Trades int
+}
+
+// candleIntervals - candles built for every market
+var candleIntervals = map[string]time.Duration{
+	"1s": time.Second,
+	"1m": time.Minute,
+	"5m": 5 * time.Minute,
+}
+
+// tapeSize - trades kept per market, candlesSize - candles kept per market and interval
+var tapeSize = 5000
+var candlesSize = 500
+
+// tape - rolling trades and candles of a market
+type tape struct {
+	sync.RWMutex
+	trades      []trade
+	candles     map[string][]candle
+	subscribers map[chan trade]bool
+}
+
+var tapes map[string]*tape
+
+func newTapes() map[string]*tape {
+	t := make(map[string]*tape)
+	for _, m := range markets {
+		t[m] = &tape{
+			candles:     make(map[string][]candle),
+			subscribers: make(map[chan trade]bool),
+		}
+	}
+	return t
+}
+
+// add a trade to the tape and its candles, and send it to the subscribers
+func (t *tape) add(tr trade) {
+	t.Lock()
+	defer t.Unlock()
+	t.trades = append(t.trades, tr)
+	if len(t.trades) > tapeSize {
+		t.trades = t.trades[len(t.trades)-tapeSize:]
+	}
+	for name, interval := range candleIntervals {
+		t.candles[name] = addToCandles(t.candles[name], interval, tr)
+	}
+	for s := range t.subscribers {
+		select {
+		case s <- tr:
+		default:
+			log.Warnf("Trade stream subscriber is too slow, dropping trade %d", tr.ID)
+		}
+	}
+}
+
+// addToCandles adds the trade to the candle of its interval. Intervals without trades get a candle
+// with the previous close and no volume, so the candles are evenly spaced
+func addToCandles(candles []candle, interval time.Duration, tr trade) []candle {
+	start := tr.Time.Truncate(interval)
+	if n := len(candles); n > 0 {
+		last := &candles[n-1]
+		if start.Before(last.Time) {
+			return candles //late trade of an older candle
+		}
+		if start.Equal(last.Time) {
+			last.High = decimal.Max(last.High, tr.Price)
+			last.Low = decimal.Min(last.Low, tr.Price)
+			last.Close = tr.Price
+			last.Volume = last.Volume.Add(tr.Size)
+			last.Trades++
+			return candles
+		}
+		prev := last.Close
+		for next := last.Time.Add(interval); next.Before(start); next = next.Add(interval) {
+			if start.Sub(next) > time.Duration(candlesSize)*interval {
+				next = start.Add(-time.Duration(candlesSize) * interval)
+			}
+			candles = append(candles, candle{Time: next, Open: prev, High: prev, Low: prev, Close: prev})
+		}
+	}
+	candles = append(candles, candle{
+		Time:   start,
+		Open:   tr.Price,
+		High:   tr.Price,
+		Low:    tr.Price,
+		Close:  tr.Price,
+		Volume: tr.Size,
+		Trades: 1,
+	})
+	if len(candles) > candlesSize {
+		candles = candles[len(candles)-candlesSize:]
+	}
+	return candles
+}
+
+func (t *tape) subscribe() chan trade {
+	s := make(chan trade, 100)
+	t.Lock()
+	t.subscribers[s] = true
+	t.Unlock()
+	return s
+}
+
+func (t *tape) unsubscribe(s chan trade) {
+	t.Lock()
+	delete(t.subscribers, s)
+	t.Unlock()
+}
+
+// addMatch adds a Coinbase match message to the tape of its market, the side of a match is the maker side
+func addMatch(market string, id int64, price, size, makerSide string, tm time.Time) {
+	t, ok := tapes[market]
+	if !ok {
+		return
+	}
+	p, err := decimal.NewFromString(price)
+	if err != nil {
+		log.Warnf("Bad match price %s: %v", price, err)
+		return
+	}
+	s, err := decimal.NewFromString(size)
+	if err != nil {
+		log.Warnf("Bad match size %s: %v", size, err)
+		return
+	}
+	side := "buy"
+	if makerSide == "buy" {
+		side = "sell"
+	}
+	t.add(trade{ID: id, Price: p, Size: s, Side: side, Time: tm})
+}
+
+func getTape(market string) (*tape, error) {
+	t, ok := tapes[strings.ToUpper(market)]
+	if !ok {
+		return nil, errors.New("Invalid market: " + market)
+	}
+	return t, nil
+}
+
+func tradeToPb(market string, tr trade) *pb.Trade {
+	return &pb.Trade{
+		Market:  market,
+		TradeID: tr.ID,
+		Price:   tr.Price.String(),
+		Size:    tr.Size.String(),
+		Side:    tr.Side,
+		Time:    tr.Time.UnixNano() / int64(time.Millisecond),
+	}
+}
+
+func (*grpcServer) GetRecentTrades(ctx context.Context, req *pb.RecentTradesRequest) (*pb.Trades, error) {
+	market := strings.ToUpper(req.Market)
+	t, err := getTape(market)
+	if err != nil {
+		return &pb.Trades{}, err
+	}
+	t.RLock()
+	defer t.RUnlock()
+	trades := t.trades
+	if req.Limit > 0 && int(req.Limit) < len(trades) {
+		trades = trades[len(trades)-int(req.Limit):]
+	}
+	res := &pb.Trades{Trades: make([]*pb.Trade, len(trades))}
+	for i, tr := range trades {
+		res.Trades[i] = tradeToPb(market, tr)
+	}
+	return res, nil
+}
+
+func (*grpcServer) GetCandles(ctx context.Context, req *pb.CandlesRequest) (*pb.Candles, error) {
+	market := strings.ToUpper(req.Market)
+	t, err := getTape(market)
+	if err != nil {
+		return &pb.Candles{}, err
+	}
+	if _, ok := candleIntervals[req.Interval]; !ok {
+		return &pb.Candles{}, errors.New("Invalid candles interval, must be 1s, 1m or 5m: " + req.Interval)
+	}
+	t.RLock()
+	defer t.RUnlock()
+	candles := t.candles[req.Interval]
+	if req.Limit > 0 && int(req.Limit) < len(candles) {
+		candles = candles[len(candles)-int(req.Limit):]
+	}
+	res := &pb.Candles{Market: market, Interval: req.Interval, Candles: make([]*pb.Candle, len(candles))}
+	for i, c := range candles {
+		res.Candles[i] = &pb.Candle{
+			Time:   c.Time.UnixNano() / int64(time.Millisecond),
+			Open:   c.Open.String(),
+			High:   c.High.String(),
+			Low:    c.Low.String(),
+			Close:  c.Close.String(),
+			Volume: c.Volume.String(),
+			Trades: int32(c.Trades),
+		}
+	}
+	return res, nil
+}
+
+func (*grpcServer) StreamTrades(req *pb.StreamTradesRequest, stream pb.TradesService_StreamTradesServer) error {
+	market := strings.ToUpper(req.Market)
+	t, err := getTape(market)
+	if err != nil {
+		return err
+	}
+	s := t.subscribe()
+	defer t.unsubscribe(s)
+	for {
+		select {
+		case tr := <-s:
+			if err := stream.Send(tradeToPb(market, tr)); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
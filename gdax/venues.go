@@ -0,0 +1,293 @@
+package gdax // gdax service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	ws "github.com/gorilla/websocket"
+	"github.com/shopspring/decimal"
+	log "github.com/sirupsen/logrus"
+	"github.com/yasushi-saito/rbtree"
+)
+
+// venueFeed - level2 feed of an exchange, it keeps the books of its venue up to date
+type venueFeed interface {
+	Name() string
+	// Run - keep the books updated, reconnecting on errors, it never returns
+	Run(books map[string]*orderbook)
+}
+
+// consolidated is the venue selector of the book made of all venues
+const consolidated = "all"
+
+// venueBooks - books of every venue by market, in the service market names ("BTC-USD")
+var venueBooks = make(map[string]map[string]*orderbook)
+
+// venueFX - factor applied to the prices of a venue so all books are in the same quote currency,
+// e.g. the USDT/USD rate for binance. 1 if not set
+var venueFX = make(map[string]decimal.Decimal)
+
+var venueFeeds = map[string]func() venueFeed{
+	"coinbase": func() venueFeed { return coinbaseFeed{} },
+	"kraken":   func() venueFeed { return &krakenFeed{Depth: 100} },
+	"binance":  func() venueFeed { return &binanceFeed{Quote: "USDT"} },
+}
+
+// feedReconnectWait - wait before reconnecting to a venue after an error
+var feedReconnectWait = 5 * time.Second
+
+func newBooks() map[string]*orderbook {
+	books := make(map[string]*orderbook)
+	for _, m := range markets {
+		books[m] = &orderbook{
+			RWMutex: &sync.RWMutex{},
+			Bids:    rbtree.NewTree(compareOrders),
+			Asks:    rbtree.NewTree(compareOrders),
+		}
+	}
+	return books
+}
+
+// startVenues starts the feeds of the given venues, coinbase uses the orderbooks of the service
+func startVenues(venues []string) error {
+	for _, v := range venues {
+		v = strings.ToLower(strings.TrimSpace(v))
+		if _, ok := venueFeeds[v]; !ok {
+			return fmt.Errorf("unknown venue %s", v)
+		}
+	}
+	for _, v := range venues {
+		v = strings.ToLower(strings.TrimSpace(v))
+		newFeed := venueFeeds[v]
+		books := newBooks()
+		if v == "coinbase" {
+			books = orderbooks
+		}
+		venueBooks[v] = books
+		if _, ok := venueFX[v]; !ok {
+			venueFX[v] = decimal.New(1, 0)
+		}
+		log.Infof("Starting %s feed, prices times %s", v, venueFX[v])
+		go newFeed().Run(books)
+	}
+	return nil
+}
+
+// parseFX reads "venue=factor,venue=factor"
+func parseFX(fx string) error {
+	for _, f := range strings.Split(fx, ",") {
+		if strings.TrimSpace(f) == "" {
+			continue
+		}
+		parts := strings.SplitN(f, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid fx %s, must be venue=factor", f)
+		}
+		factor, err := decimal.NewFromString(strings.TrimSpace(parts[1]))
+		if err != nil || factor.Sign() <= 0 {
+			return fmt.Errorf("invalid fx factor %s", f)
+		}
+		venueFX[strings.ToLower(strings.TrimSpace(parts[0]))] = factor
+	}
+	return nil
+}
+
+// getBook - book of a venue, "coinbase" if venue is empty
+func getBook(venue, market string) (*orderbook, error) {
+	if venue == "" {
+		venue = "coinbase"
+	}
+	books, ok := venueBooks[venue]
+	if !ok {
+		return nil, errors.New("Invalid venue: " + venue)
+	}
+	book, ok := books[market]
+	if !ok {
+		return nil, errors.New("Invalid market: " + market)
+	}
+	return book, nil
+}
+
+// coinbaseFeed - the Coinbase level2 feed of the service
+type coinbaseFeed struct{}
+
+func (coinbaseFeed) Name() string {
+	return "coinbase"
+}
+
+func (coinbaseFeed) Run(books map[string]*orderbook) {
+	gdaxSubscribe()
+	readGdax()
+}
+
+// krakenFeed - Kraken level2 feed, websocket api v2 book channel
+type krakenFeed struct {
+	Depth   int               //levels kept on each side, Kraken only sends updates for these
+	symbols map[string]string //Kraken symbol to market
+}
+
+type krakenLevel struct {
+	Price decimal.Decimal `json:"price"`
+	Qty   decimal.Decimal `json:"qty"`
+}
+
+type krakenMessage struct {
+	Channel string `json:"channel"`
+	Type    string `json:"type"`
+	Method  string `json:"method"`
+	Success *bool  `json:"success"`
+	Error   string `json:"error"`
+	Data    []struct {
+		Symbol string        `json:"symbol"`
+		Bids   []krakenLevel `json:"bids"`
+		Asks   []krakenLevel `json:"asks"`
+	} `json:"data"`
+}
+
+func (k *krakenFeed) Name() string {
+	return "kraken"
+}
+
+func (k *krakenFeed) Run(books map[string]*orderbook) {
+	k.symbols = make(map[string]string)
+	var symbols []string
+	for _, m := range markets {
+		s := strings.Replace(m, "-", "/", 1)
+		k.symbols[s] = m
+		symbols = append(symbols, s)
+	}
+	subscribe := map[string]interface{}{
+		"method": "subscribe",
+		"params": map[string]interface{}{
+			"channel":  "book",
+			"symbol":   symbols,
+			"depth":    k.Depth,
+			"snapshot": true,
+		},
+	}
+	runFeed(k.Name(), "wss://ws.kraken.com/v2", subscribe, books, func(data []byte) error {
+		var m krakenMessage
+		if err := json.Unmarshal(data, &m); err != nil {
+			return err
+		}
+		if m.Success != nil && !*m.Success {
+			log.Warnf("kraken: %s failed: %s", m.Method, m.Error)
+			return nil
+		}
+		if m.Channel != "book" {
+			return nil
+		}
+		for _, d := range m.Data {
+			book, ok := books[k.symbols[d.Symbol]]
+			if !ok {
+				continue
+			}
+			book.Lock()
+			if m.Type == "snapshot" {
+				book.clear()
+			}
+			for _, l := range d.Bids {
+				book.updateBid(l.Price.String(), l.Qty.String())
+			}
+			for _, l := range d.Asks {
+				book.updateAsk(l.Price.String(), l.Qty.String())
+			}
+			book.truncate(k.Depth)
+			book.Unlock()
+		}
+		return nil
+	})
+}
+
+// binanceFeed - Binance partial book feed, every message is a snapshot of the best 20 levels.
+// Binance has no USD markets, Quote replaces USD in the market names
+type binanceFeed struct {
+	Quote   string
+	streams map[string]string //stream name to market
+}
+
+type binanceMessage struct {
+	Stream string `json:"stream"`
+	Data   struct {
+		Bids [][2]string `json:"bids"`
+		Asks [][2]string `json:"asks"`
+	} `json:"data"`
+}
+
+func (b *binanceFeed) Name() string {
+	return "binance"
+}
+
+func (b *binanceFeed) Run(books map[string]*orderbook) {
+	b.streams = make(map[string]string)
+	var streams []string
+	for _, m := range markets {
+		symbol := strings.Replace(m, "-USD", "-"+b.Quote, 1)
+		stream := strings.ToLower(strings.Replace(symbol, "-", "", 1)) + "@depth20@100ms"
+		b.streams[stream] = m
+		streams = append(streams, stream)
+	}
+	url := "wss://stream.binance.com:9443/stream?streams=" + strings.Join(streams, "/")
+	runFeed(b.Name(), url, nil, books, func(data []byte) error {
+		var m binanceMessage
+		if err := json.Unmarshal(data, &m); err != nil {
+			return err
+		}
+		book, ok := books[b.streams[m.Stream]]
+		if !ok {
+			return nil
+		}
+		book.Lock()
+		book.clear()
+		for _, l := range m.Data.Bids {
+			book.updateBid(l[0], l[1])
+		}
+		for _, l := range m.Data.Asks {
+			book.updateAsk(l[0], l[1])
+		}
+		book.Unlock()
+		return nil
+	})
+}
+
+// runFeed connects to a venue websocket, sends subscribe if not nil and passes every message to
+// handle. On errors the books are cleared and it connects again
+func runFeed(venue, url string, subscribe interface{}, books map[string]*orderbook, handle func([]byte) error) {
+	var dialer ws.Dialer
+	for {
+		err := func() error {
+			log.Infof("Connecting to %s websocket...", venue)
+			conn, _, err := dialer.Dial(url, nil)
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+			if subscribe != nil {
+				if err := conn.WriteJSON(subscribe); err != nil {
+					return err
+				}
+			}
+			log.Infof("Connected to %s", venue)
+			for {
+				_, data, err := conn.ReadMessage()
+				if err != nil {
+					return err
+				}
+				if err := handle(data); err != nil {
+					log.Warnf("%s: unable to process message %s: %v", venue, data, err)
+				}
+			}
+		}()
+		log.Warnf("%s websocket error: %v, reconnecting in %s", venue, err, feedReconnectWait)
+		for _, book := range books {
+			book.Lock()
+			book.clear()
+			book.Unlock()
+		}
+		time.Sleep(feedReconnectWait)
+	}
+}
@@ -0,0 +1,143 @@
+package grpcconf
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"flag"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// authHeader - metadata key of the token, sent as "Bearer <token>"
+const authHeader = "authorization"
+
+// Auth - authentication of the clients of a grpc server, a request is accepted if it has the
+// shared Token or a client certificate whose common name or DNS name is in Clients. No
+// authentication if both are empty
+type Auth struct {
+	Token   string //shared token of the service
+	Clients string //comma separated names of the client certificates allowed, needs mTLS
+}
+
+// Enabled - true if the clients are authenticated
+func (a Auth) Enabled() bool {
+	return a.Token != "" || a.Clients != ""
+}
+
+// Flags - adds the -<prefix>auth-token and -<prefix>auth-clients flags to fs, defaulting to the
+// <env>_AUTH_TOKEN and <env>_AUTH_CLIENTS environment variables
+func (a *Auth) Flags(fs *flag.FlagSet, prefix, env string) {
+	fs.StringVar(&a.Token, prefix+"auth-token", Env(env+"_AUTH_TOKEN", a.Token), "shared token the clients must send, no authentication if it and the clients are empty")
+	fs.StringVar(&a.Clients, prefix+"auth-clients", Env(env+"_AUTH_CLIENTS", a.Clients), "comma separated names of the client certificates allowed, needs the TLS CA (mTLS)")
+}
+
+// ServerOptions - interceptors rejecting the unauthenticated requests, none without authentication.
+// t is the TLS of the server, client certificates need its CA
+func (a Auth) ServerOptions(t TLS) ([]grpc.ServerOption, error) {
+	if !a.Enabled() {
+		return nil, nil
+	}
+	if a.Clients != "" && t.CAFile == "" {
+		return nil, errors.New("ServerOptions-> client certificate names need the TLS CA of the clients")
+	}
+	if a.Token != "" && !t.Enabled() {
+		log.Warn("The grpc auth token is sent in plain text, use TLS if the network is not trusted")
+	}
+	clients := make(map[string]bool)
+	for _, c := range strings.Split(a.Clients, ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			clients[c] = true
+		}
+	}
+	unary := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := a.authorize(ctx, clients, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+	stream := func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := a.authorize(ss.Context(), clients, info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+	return []grpc.ServerOption{grpc.UnaryInterceptor(unary), grpc.StreamInterceptor(stream)}, nil
+}
+
+func (a Auth) authorize(ctx context.Context, clients map[string]bool, method string) error {
+	if a.Token != "" {
+		md, _ := metadata.FromIncomingContext(ctx)
+		for _, v := range md.Get(authHeader) {
+			if subtle.ConstantTimeCompare([]byte(v), []byte("Bearer "+a.Token)) == 1 {
+				return nil
+			}
+		}
+	}
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "unauthenticated")
+	}
+	if info, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(clients) > 0 {
+		for _, chain := range info.State.VerifiedChains {
+			if len(chain) == 0 {
+				continue
+			}
+			if clients[chain[0].Subject.CommonName] {
+				return nil
+			}
+			for _, name := range chain[0].DNSNames {
+				if clients[name] {
+					return nil
+				}
+			}
+		}
+	}
+	log.Warnf("Unauthenticated grpc request %s from %s", method, p.Addr)
+	return status.Error(codes.Unauthenticated, "unauthenticated")
+}
+
+// tokenCredentials - per request credentials sending a shared token
+type tokenCredentials string
+
+func (t tokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{authHeader: "Bearer " + string(t)}, nil
+}
+
+// RequireTransportSecurity is false so the services can be used in plain text inside a trusted network
+func (t tokenCredentials) RequireTransportSecurity() bool {
+	return false
+}
+
+// DialOptions - grpc dial options of the TLS t sending token in every request, no token if empty
+func DialOptions(t TLS, token string) ([]grpc.DialOption, error) {
+	opt, err := t.DialOption()
+	if err != nil {
+		return nil, err
+	}
+	opts := []grpc.DialOption{opt}
+	if token != "" {
+		opts = append(opts, grpc.WithPerRPCCredentials(tokenCredentials(token)))
+	}
+	return opts, nil
+}
+
+// ServerOptions - grpc server options of the TLS t and the authentication a
+func ServerOptions(t TLS, a Auth) ([]grpc.ServerOption, error) {
+	opts, err := t.ServerOptions()
+	if err != nil {
+		return nil, err
+	}
+	authOpts, err := a.ServerOptions(t)
+	if err != nil {
+		return nil, err
+	}
+	return append(opts, authOpts...), nil
+}
@@ -0,0 +1,116 @@
+package grpcconf // endpoints, TLS and authentication of the grpc services and their clients
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// TLS - certificates of a grpc server or client, plain text if CertFile and CAFile are empty.
+// A server with CAFile requires client certificates signed by it (mTLS), a client with CertFile
+// presents it to the server
+type TLS struct {
+	CertFile   string //certificate, of the server or of the client for mTLS
+	KeyFile    string //private key of CertFile
+	CAFile     string //server: CA of the client certificates, client: CA of the server certificate, system CAs if empty
+	ServerName string //client: name in the server certificate, the host of the address if empty
+}
+
+// Enabled - true if TLS is used
+func (t TLS) Enabled() bool {
+	return t.CertFile != "" || t.CAFile != ""
+}
+
+// Flags - adds the -<prefix>tls-cert, -<prefix>tls-key, -<prefix>tls-ca and -<prefix>tls-server-name
+// flags to fs, defaulting to the <env>_TLS_CERT, <env>_TLS_KEY, <env>_TLS_CA and <env>_TLS_SERVER_NAME
+// environment variables
+func (t *TLS) Flags(fs *flag.FlagSet, prefix, env string) {
+	fs.StringVar(&t.CertFile, prefix+"tls-cert", Env(env+"_TLS_CERT", t.CertFile), "TLS certificate file, plain text if it and the CA are empty")
+	fs.StringVar(&t.KeyFile, prefix+"tls-key", Env(env+"_TLS_KEY", t.KeyFile), "TLS private key file")
+	fs.StringVar(&t.CAFile, prefix+"tls-ca", Env(env+"_TLS_CA", t.CAFile), "TLS CA file: of the client certificates on a server (mTLS), of the server certificate on a client")
+	fs.StringVar(&t.ServerName, prefix+"tls-server-name", Env(env+"_TLS_SERVER_NAME", t.ServerName), "name in the server certificate, the host of the address if empty")
+}
+
+// ServerOptions - grpc server options of t, none for plain text
+func (t TLS) ServerOptions() ([]grpc.ServerOption, error) {
+	if !t.Enabled() {
+		return nil, nil
+	}
+	if t.CertFile == "" || t.KeyFile == "" {
+		return nil, errors.New("ServerOptions-> a TLS server needs a certificate and its key")
+	}
+	cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("ServerOptions-> %v", err)
+	}
+	config := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	if t.CAFile != "" {
+		pool, err := loadCA(t.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("ServerOptions-> %v", err)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return []grpc.ServerOption{grpc.Creds(credentials.NewTLS(config))}, nil
+}
+
+// DialOption - grpc dial option of t, insecure for plain text
+func (t TLS) DialOption() (grpc.DialOption, error) {
+	if !t.Enabled() {
+		return grpc.WithInsecure(), nil
+	}
+	config := &tls.Config{ServerName: t.ServerName, MinVersion: tls.VersionTLS12}
+	if t.CAFile != "" {
+		pool, err := loadCA(t.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("DialOption-> %v", err)
+		}
+		config.RootCAs = pool
+	}
+	if t.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("DialOption-> %v", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return grpc.WithTransportCredentials(credentials.NewTLS(config)), nil
+}
+
+func loadCA(filename string) (*x509.CertPool, error) {
+	pem, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates in %s", filename)
+	}
+	return pool, nil
+}
+
+// Env - value of the environment variable name, def if it is not set
+func Env(name, def string) string {
+	if v, ok := os.LookupEnv(name); ok {
+		return v
+	}
+	return def
+}
+
+// Endpoint - first non empty of a flag, the environment variable env, a configuration value and def
+func Endpoint(flagValue, env, configValue, def string) string {
+	for _, v := range []string{flagValue, os.Getenv(env), configValue} {
+		if v != "" {
+			return v
+		}
+	}
+	return def
+}
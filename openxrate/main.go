@@ -1,4 +1,4 @@
-package main // openxrate - Open Exchange Rate updater
+package openxrate // openxrate - Open Exchange Rate updater
 
 import (
 	"context"
@@ -18,6 +18,7 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
 
+	"git.vmo.mx/Tauros/tradingbot/grpcconf"
 	pb "git.vmo.mx/Tauros/tradingbot/proto"
 )
 
@@ -34,7 +35,7 @@ type credentials struct { //todo: put in lib
 		Email string `json:"email"`
 		Password string `json:"password"`
 		Websocket string `json:"websocket"`
-		BaseAPIUrl string `json:"base_api_url` 
+		BaseAPIUrl string `json:"base_api_url"`
 	} `json:"tauros"`
 	OpenExchangeRates struct {
 		Token string `json:"token"`
@@ -51,17 +52,18 @@ var oxGrpcServer *grpc.Server
 var mux sync.RWMutex
 var oxToken string
 
-func loadCredentialsFile(filename string) {
+func loadCredentialsFile(filename string) error {
 	log.Infof("Using credentials file: %s", filename)
 	var creds credentials
 	in, err := ioutil.ReadFile(filename)
-	if err!= nil {
-		log.Fatalf("Unable to load credentials file: %v", err)
+	if err != nil {
+		return fmt.Errorf("unable to load credentials file: %v", err)
 	}
 	if err := json.Unmarshal(in, &creds); err != nil {
-		log.Fatalf("Unable to unmarshal json file: %v", err)
+		return fmt.Errorf("unable to unmarshal json file: %v", err)
 	}
 	oxToken = creds.OpenExchangeRates.Token
+	return nil
 }
 
 func (*grpcServer) GetOxRate(ctx context.Context, req *pb.OxRequest) (*pb.OxRate, error) {
@@ -102,16 +104,56 @@ func getRate(apiToken string) (rate, error) {
 	return mxnRate, nil
 }
 
-func startGrpcServer(port string) {
+// Register - adds the exchange rate service to s
+func Register(s *grpc.Server) {
+	pb.RegisterOxServiceServer(s, &grpcServer{})
+}
+
+// Start - gets the rate with the openexchangerates token of credentialsFile and updates it every
+// 15 minutes, keeping the last one if an update fails
+func Start(credentialsFile string) error {
+	if err := loadCredentialsFile(credentialsFile); err != nil {
+		return fmt.Errorf("Start-> %v", err)
+	}
+	r, err := getRate(oxToken)
+	if err != nil {
+		return fmt.Errorf("Start-> %v", err)
+	}
+	mux.Lock()
+	currentRate = r
+	mux.Unlock()
+	go func() {
+		for {
+			time.Sleep(time.Duration(15) * time.Minute)
+			r, err := getRate(oxToken)
+			if err != nil {
+				log.Errorf("Unable to update the rate, keeping the last one: %v", err)
+				continue
+			}
+			mux.Lock()
+			currentRate = r
+			mux.Unlock()
+		}
+	}()
+	return nil
+}
+
+func startGrpcServer(addr string, tlsConf grpcconf.TLS, auth grpcconf.Auth, withReflection bool) {
 	log.Info("Starting grpc server..")
-	listener, err := net.Listen("tcp", ":"+port)
+	opts, err := grpcconf.ServerOptions(tlsConf, auth)
+	if err != nil {
+		log.Fatalf("Invalid TLS or auth configuration: %v", err)
+	}
+	listener, err := net.Listen("tcp", addr)
 	if err != nil {
-		log.Fatalf("Failed to open listening port on %s, %v", port, err)
+		log.Fatalf("Failed to open listening port on %s, %v", addr, err)
 	}
-	oxGrpcServer = grpc.NewServer()
-	pb.RegisterOxServiceServer(oxGrpcServer, &grpcServer{})
-	reflection.Register(oxGrpcServer)
-	log.Infof("Done. Waiting for grpc requests on port %s...", port)
+	oxGrpcServer = grpc.NewServer(opts...)
+	Register(oxGrpcServer)
+	if withReflection {
+		reflection.Register(oxGrpcServer)
+	}
+	log.Infof("Done. Waiting for grpc requests at %s (TLS %t, auth %t, reflection %t)...", addr, tlsConf.Enabled(), auth.Enabled(), withReflection)
 	err = oxGrpcServer.Serve(listener)
 	if err != nil {
 		log.Fatalf("Unable to start listening for grpce: %v", err)
@@ -128,7 +170,14 @@ func (f *logFormatter) Format(entry *log.Entry) ([]byte, error) {
 	return []byte(fmt.Sprintf("%s %s %s\n", f.LevelDesc[entry.Level], timestamp, entry.Message)), nil
 }
 
-func main() {
+// Main runs the exchange rate service with the credentials file given as argument until SIGINT or SIGTERM
+func Main() {
+	listen := flag.String("listen", grpcconf.Env("OX_LISTEN", ":2223"), "address of the grpc server, env OX_LISTEN")
+	var tlsConf grpcconf.TLS
+	tlsConf.Flags(flag.CommandLine, "", "OX")
+	var auth grpcconf.Auth
+	auth.Flags(flag.CommandLine, "", "OX")
+	withReflection := flag.Bool("reflection", grpcconf.Env("OX_REFLECTION", "") == "true", "serve the grpc reflection service, env OX_REFLECTION=true")
 	flag.Parse()
 
 	logFormatter := new(logFormatter)
@@ -136,20 +185,10 @@ func main() {
 	logFormatter.LevelDesc = []string{"PANIC", "FATAL", "ERROR", "WARNI", "INFOR", "DEBUG","TRACE"}
 	log.SetFormatter(logFormatter)
 
-	loadCredentialsFile(flag.Arg(0))
-	go func() {
-		var err error
-		for {
-			mux.Lock()
-			currentRate, err = getRate(oxToken)
-			mux.Unlock()
-			if err != nil { //todo check if error is http timeout, if so try several times
-				log.Fatalf("%v", err)
-			}
-			time.Sleep(time.Duration(15) * time.Minute)
-		}
-	}()
-	go startGrpcServer("2223") //todo port in parameter
+	if err := Start(flag.Arg(0)); err != nil {
+		log.Fatalf("%v", err)
+	}
+	go startGrpcServer(*listen, tlsConf, auth, *withReflection)
 
 	c := make(chan os.Signal, 2)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
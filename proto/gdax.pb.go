@@ -25,9 +25,15 @@ var _ = math.Inf
 // = proto.ProtoPackageIsVersion3 // please upgrade the proto package
 
 type SpreadPriceRequest struct {
-	Market               string   `protobuf:"bytes,1,opt,name=Market,proto3" json:"Market,omitempty"`
-	Side                 string   `protobuf:"bytes,2,opt,name=Side,proto3" json:"Side,omitempty"`
-	Depth                string   `protobuf:"bytes,3,opt,name=Depth,proto3" json:"Depth,omitempty"`
+	Market string `protobuf:"bytes,1,opt,name=Market,proto3" json:"Market,omitempty"`
+	Side   string `protobuf:"bytes,2,opt,name=Side,proto3" json:"Side,omitempty"`
+	Depth  string `protobuf:"bytes,3,opt,name=Depth,proto3" json:"Depth,omitempty"`
+	Venue  string `protobuf:"bytes,4,opt,name=Venue,proto3" json:"Venue,omitempty"`
+	// "level" if empty: price of the level where the amounts add up to Depth
+	// "vwap": average price to fill Depth
+	// "notional": price of the level where the values add up to Depth, in quote currency
+	// "slippage": price Depth bps away from the best price, Size is what can be filled up to it
+	Mode                 string   `protobuf:"bytes,5,opt,name=Mode,proto3" json:"Mode,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -79,9 +85,25 @@ func (m *SpreadPriceRequest) GetDepth() string {
 	return ""
 }
 
+func (m *SpreadPriceRequest) GetVenue() string {
+	if m != nil {
+		return m.Venue
+	}
+	return ""
+}
+
+func (m *SpreadPriceRequest) GetMode() string {
+	if m != nil {
+		return m.Mode
+	}
+	return ""
+}
+
 type SpreadPrice struct {
 	Market               string   `protobuf:"bytes,1,opt,name=Market,proto3" json:"Market,omitempty"`
 	Price                string   `protobuf:"bytes,2,opt,name=Price,proto3" json:"Price,omitempty"`
+	Size                 string   `protobuf:"bytes,3,opt,name=Size,proto3" json:"Size,omitempty"`
+	Levels               int32    `protobuf:"varint,4,opt,name=Levels,proto3" json:"Levels,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -126,8 +148,23 @@ func (m *SpreadPrice) GetPrice() string {
 	return ""
 }
 
+func (m *SpreadPrice) GetSize() string {
+	if m != nil {
+		return m.Size
+	}
+	return ""
+}
+
+func (m *SpreadPrice) GetLevels() int32 {
+	if m != nil {
+		return m.Levels
+	}
+	return 0
+}
+
 type TickerRequest struct {
 	Market               string   `protobuf:"bytes,1,opt,name=Market,proto3" json:"Market,omitempty"`
+	Venue                string   `protobuf:"bytes,2,opt,name=Venue,proto3" json:"Venue,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -165,6 +202,13 @@ func (m *TickerRequest) GetMarket() string {
 	return ""
 }
 
+func (m *TickerRequest) GetVenue() string {
+	if m != nil {
+		return m.Venue
+	}
+	return ""
+}
+
 type Ticker struct {
 	Market               string   `protobuf:"bytes,1,opt,name=Market,proto3" json:"Market,omitempty"`
 	MaxBid               string   `protobuf:"bytes,2,opt,name=MaxBid,proto3" json:"MaxBid,omitempty"`
@@ -220,32 +264,460 @@ func (m *Ticker) GetMinAsk() string {
 	return ""
 }
 
+type Trade struct {
+	Market               string   `protobuf:"bytes,1,opt,name=Market,proto3" json:"Market,omitempty"`
+	TradeID              int64    `protobuf:"varint,2,opt,name=TradeID,proto3" json:"TradeID,omitempty"`
+	Price                string   `protobuf:"bytes,3,opt,name=Price,proto3" json:"Price,omitempty"`
+	Size                 string   `protobuf:"bytes,4,opt,name=Size,proto3" json:"Size,omitempty"`
+	Side                 string   `protobuf:"bytes,5,opt,name=Side,proto3" json:"Side,omitempty"`
+	Time                 int64    `protobuf:"varint,6,opt,name=Time,proto3" json:"Time,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Trade) Reset()         { *m = Trade{} }
+func (m *Trade) String() string { return proto.CompactTextString(m) }
+func (*Trade) ProtoMessage()    {}
+func (*Trade) Descriptor() ([]byte, []int) {
+	return fileDescriptor_efa8a912ee610f1a, []int{4}
+}
+
+func (m *Trade) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Trade.Unmarshal(m, b)
+}
+func (m *Trade) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Trade.Marshal(b, m, deterministic)
+}
+func (m *Trade) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Trade.Merge(m, src)
+}
+func (m *Trade) XXX_Size() int {
+	return xxx_messageInfo_Trade.Size(m)
+}
+func (m *Trade) XXX_DiscardUnknown() {
+	xxx_messageInfo_Trade.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Trade proto.InternalMessageInfo
+
+func (m *Trade) GetMarket() string {
+	if m != nil {
+		return m.Market
+	}
+	return ""
+}
+
+func (m *Trade) GetTradeID() int64 {
+	if m != nil {
+		return m.TradeID
+	}
+	return 0
+}
+
+func (m *Trade) GetPrice() string {
+	if m != nil {
+		return m.Price
+	}
+	return ""
+}
+
+func (m *Trade) GetSize() string {
+	if m != nil {
+		return m.Size
+	}
+	return ""
+}
+
+func (m *Trade) GetSide() string {
+	if m != nil {
+		return m.Side
+	}
+	return ""
+}
+
+func (m *Trade) GetTime() int64 {
+	if m != nil {
+		return m.Time
+	}
+	return 0
+}
+
+type RecentTradesRequest struct {
+	Market               string   `protobuf:"bytes,1,opt,name=Market,proto3" json:"Market,omitempty"`
+	Limit                int32    `protobuf:"varint,2,opt,name=Limit,proto3" json:"Limit,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RecentTradesRequest) Reset()         { *m = RecentTradesRequest{} }
+func (m *RecentTradesRequest) String() string { return proto.CompactTextString(m) }
+func (*RecentTradesRequest) ProtoMessage()    {}
+func (*RecentTradesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_efa8a912ee610f1a, []int{5}
+}
+
+func (m *RecentTradesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RecentTradesRequest.Unmarshal(m, b)
+}
+func (m *RecentTradesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RecentTradesRequest.Marshal(b, m, deterministic)
+}
+func (m *RecentTradesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RecentTradesRequest.Merge(m, src)
+}
+func (m *RecentTradesRequest) XXX_Size() int {
+	return xxx_messageInfo_RecentTradesRequest.Size(m)
+}
+func (m *RecentTradesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RecentTradesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RecentTradesRequest proto.InternalMessageInfo
+
+func (m *RecentTradesRequest) GetMarket() string {
+	if m != nil {
+		return m.Market
+	}
+	return ""
+}
+
+func (m *RecentTradesRequest) GetLimit() int32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+type Trades struct {
+	Trades               []*Trade `protobuf:"bytes,1,rep,name=Trades,proto3" json:"Trades,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Trades) Reset()         { *m = Trades{} }
+func (m *Trades) String() string { return proto.CompactTextString(m) }
+func (*Trades) ProtoMessage()    {}
+func (*Trades) Descriptor() ([]byte, []int) {
+	return fileDescriptor_efa8a912ee610f1a, []int{6}
+}
+
+func (m *Trades) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Trades.Unmarshal(m, b)
+}
+func (m *Trades) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Trades.Marshal(b, m, deterministic)
+}
+func (m *Trades) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Trades.Merge(m, src)
+}
+func (m *Trades) XXX_Size() int {
+	return xxx_messageInfo_Trades.Size(m)
+}
+func (m *Trades) XXX_DiscardUnknown() {
+	xxx_messageInfo_Trades.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Trades proto.InternalMessageInfo
+
+func (m *Trades) GetTrades() []*Trade {
+	if m != nil {
+		return m.Trades
+	}
+	return nil
+}
+
+type CandlesRequest struct {
+	Market               string   `protobuf:"bytes,1,opt,name=Market,proto3" json:"Market,omitempty"`
+	Interval             string   `protobuf:"bytes,2,opt,name=Interval,proto3" json:"Interval,omitempty"`
+	Limit                int32    `protobuf:"varint,3,opt,name=Limit,proto3" json:"Limit,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CandlesRequest) Reset()         { *m = CandlesRequest{} }
+func (m *CandlesRequest) String() string { return proto.CompactTextString(m) }
+func (*CandlesRequest) ProtoMessage()    {}
+func (*CandlesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_efa8a912ee610f1a, []int{7}
+}
+
+func (m *CandlesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CandlesRequest.Unmarshal(m, b)
+}
+func (m *CandlesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CandlesRequest.Marshal(b, m, deterministic)
+}
+func (m *CandlesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CandlesRequest.Merge(m, src)
+}
+func (m *CandlesRequest) XXX_Size() int {
+	return xxx_messageInfo_CandlesRequest.Size(m)
+}
+func (m *CandlesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CandlesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CandlesRequest proto.InternalMessageInfo
+
+func (m *CandlesRequest) GetMarket() string {
+	if m != nil {
+		return m.Market
+	}
+	return ""
+}
+
+func (m *CandlesRequest) GetInterval() string {
+	if m != nil {
+		return m.Interval
+	}
+	return ""
+}
+
+func (m *CandlesRequest) GetLimit() int32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+type Candle struct {
+	Time                 int64    `protobuf:"varint,1,opt,name=Time,proto3" json:"Time,omitempty"`
+	Open                 string   `protobuf:"bytes,2,opt,name=Open,proto3" json:"Open,omitempty"`
+	High                 string   `protobuf:"bytes,3,opt,name=High,proto3" json:"High,omitempty"`
+	Low                  string   `protobuf:"bytes,4,opt,name=Low,proto3" json:"Low,omitempty"`
+	Close                string   `protobuf:"bytes,5,opt,name=Close,proto3" json:"Close,omitempty"`
+	Volume               string   `protobuf:"bytes,6,opt,name=Volume,proto3" json:"Volume,omitempty"`
+	Trades               int32    `protobuf:"varint,7,opt,name=Trades,proto3" json:"Trades,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Candle) Reset()         { *m = Candle{} }
+func (m *Candle) String() string { return proto.CompactTextString(m) }
+func (*Candle) ProtoMessage()    {}
+func (*Candle) Descriptor() ([]byte, []int) {
+	return fileDescriptor_efa8a912ee610f1a, []int{8}
+}
+
+func (m *Candle) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Candle.Unmarshal(m, b)
+}
+func (m *Candle) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Candle.Marshal(b, m, deterministic)
+}
+func (m *Candle) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Candle.Merge(m, src)
+}
+func (m *Candle) XXX_Size() int {
+	return xxx_messageInfo_Candle.Size(m)
+}
+func (m *Candle) XXX_DiscardUnknown() {
+	xxx_messageInfo_Candle.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Candle proto.InternalMessageInfo
+
+func (m *Candle) GetTime() int64 {
+	if m != nil {
+		return m.Time
+	}
+	return 0
+}
+
+func (m *Candle) GetOpen() string {
+	if m != nil {
+		return m.Open
+	}
+	return ""
+}
+
+func (m *Candle) GetHigh() string {
+	if m != nil {
+		return m.High
+	}
+	return ""
+}
+
+func (m *Candle) GetLow() string {
+	if m != nil {
+		return m.Low
+	}
+	return ""
+}
+
+func (m *Candle) GetClose() string {
+	if m != nil {
+		return m.Close
+	}
+	return ""
+}
+
+func (m *Candle) GetVolume() string {
+	if m != nil {
+		return m.Volume
+	}
+	return ""
+}
+
+func (m *Candle) GetTrades() int32 {
+	if m != nil {
+		return m.Trades
+	}
+	return 0
+}
+
+type Candles struct {
+	Market               string    `protobuf:"bytes,1,opt,name=Market,proto3" json:"Market,omitempty"`
+	Interval             string    `protobuf:"bytes,2,opt,name=Interval,proto3" json:"Interval,omitempty"`
+	Candles              []*Candle `protobuf:"bytes,3,rep,name=Candles,proto3" json:"Candles,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
+	XXX_unrecognized     []byte    `json:"-"`
+	XXX_sizecache        int32     `json:"-"`
+}
+
+func (m *Candles) Reset()         { *m = Candles{} }
+func (m *Candles) String() string { return proto.CompactTextString(m) }
+func (*Candles) ProtoMessage()    {}
+func (*Candles) Descriptor() ([]byte, []int) {
+	return fileDescriptor_efa8a912ee610f1a, []int{9}
+}
+
+func (m *Candles) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Candles.Unmarshal(m, b)
+}
+func (m *Candles) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Candles.Marshal(b, m, deterministic)
+}
+func (m *Candles) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Candles.Merge(m, src)
+}
+func (m *Candles) XXX_Size() int {
+	return xxx_messageInfo_Candles.Size(m)
+}
+func (m *Candles) XXX_DiscardUnknown() {
+	xxx_messageInfo_Candles.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Candles proto.InternalMessageInfo
+
+func (m *Candles) GetMarket() string {
+	if m != nil {
+		return m.Market
+	}
+	return ""
+}
+
+func (m *Candles) GetInterval() string {
+	if m != nil {
+		return m.Interval
+	}
+	return ""
+}
+
+func (m *Candles) GetCandles() []*Candle {
+	if m != nil {
+		return m.Candles
+	}
+	return nil
+}
+
+type StreamTradesRequest struct {
+	Market               string   `protobuf:"bytes,1,opt,name=Market,proto3" json:"Market,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *StreamTradesRequest) Reset()         { *m = StreamTradesRequest{} }
+func (m *StreamTradesRequest) String() string { return proto.CompactTextString(m) }
+func (*StreamTradesRequest) ProtoMessage()    {}
+func (*StreamTradesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_efa8a912ee610f1a, []int{10}
+}
+
+func (m *StreamTradesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StreamTradesRequest.Unmarshal(m, b)
+}
+func (m *StreamTradesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StreamTradesRequest.Marshal(b, m, deterministic)
+}
+func (m *StreamTradesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StreamTradesRequest.Merge(m, src)
+}
+func (m *StreamTradesRequest) XXX_Size() int {
+	return xxx_messageInfo_StreamTradesRequest.Size(m)
+}
+func (m *StreamTradesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_StreamTradesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StreamTradesRequest proto.InternalMessageInfo
+
+func (m *StreamTradesRequest) GetMarket() string {
+	if m != nil {
+		return m.Market
+	}
+	return ""
+}
+
 func init() {
 	proto.RegisterType((*SpreadPriceRequest)(nil), "pb.SpreadPriceRequest")
 	proto.RegisterType((*SpreadPrice)(nil), "pb.SpreadPrice")
 	proto.RegisterType((*TickerRequest)(nil), "pb.TickerRequest")
 	proto.RegisterType((*Ticker)(nil), "pb.Ticker")
+	proto.RegisterType((*Trade)(nil), "pb.Trade")
+	proto.RegisterType((*RecentTradesRequest)(nil), "pb.RecentTradesRequest")
+	proto.RegisterType((*Trades)(nil), "pb.Trades")
+	proto.RegisterType((*CandlesRequest)(nil), "pb.CandlesRequest")
+	proto.RegisterType((*Candle)(nil), "pb.Candle")
+	proto.RegisterType((*Candles)(nil), "pb.Candles")
+	proto.RegisterType((*StreamTradesRequest)(nil), "pb.StreamTradesRequest")
 }
 
 func init() { proto.RegisterFile("gdax.proto", fileDescriptor_efa8a912ee610f1a) }
 
 var fileDescriptor_efa8a912ee610f1a = []byte{
-	// 231 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0xe2, 0x4a, 0x4f, 0x49, 0xac,
-	0xd0, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0x62, 0x2a, 0x48, 0x52, 0x0a, 0xe3, 0x12, 0x0a, 0x2e,
-	0x28, 0x4a, 0x4d, 0x4c, 0x09, 0x28, 0xca, 0x4c, 0x4e, 0x0d, 0x4a, 0x2d, 0x2c, 0x4d, 0x2d, 0x2e,
-	0x11, 0x12, 0xe3, 0x62, 0xf3, 0x4d, 0x2c, 0xca, 0x4e, 0x2d, 0x91, 0x60, 0x54, 0x60, 0xd4, 0xe0,
-	0x0c, 0x82, 0xf2, 0x84, 0x84, 0xb8, 0x58, 0x82, 0x33, 0x53, 0x52, 0x25, 0x98, 0xc0, 0xa2, 0x60,
-	0xb6, 0x90, 0x08, 0x17, 0xab, 0x4b, 0x6a, 0x41, 0x49, 0x86, 0x04, 0x33, 0x58, 0x10, 0xc2, 0x51,
-	0xb2, 0xe6, 0xe2, 0x46, 0x32, 0x17, 0xa7, 0x81, 0x22, 0x5c, 0xac, 0x60, 0x05, 0x50, 0x13, 0x21,
-	0x1c, 0x25, 0x75, 0x2e, 0xde, 0x90, 0xcc, 0xe4, 0xec, 0xd4, 0x22, 0x02, 0xee, 0x51, 0x0a, 0xe0,
-	0x62, 0x83, 0x28, 0xc4, 0x69, 0x01, 0x58, 0xbc, 0xc2, 0x29, 0x33, 0x05, 0x6a, 0x03, 0x94, 0x07,
-	0x16, 0xcf, 0xcc, 0x73, 0x2c, 0xce, 0x86, 0x3a, 0x1b, 0xca, 0x33, 0x0a, 0x44, 0x09, 0x8f, 0xe0,
-	0xd4, 0xa2, 0x32, 0x90, 0xf3, 0xad, 0xb9, 0xf8, 0xdc, 0x53, 0x4b, 0x50, 0x3c, 0xa4, 0x57, 0x90,
-	0xa4, 0x87, 0x19, 0x72, 0x52, 0xfc, 0x68, 0xe2, 0x4a, 0x0c, 0x46, 0xb6, 0x30, 0xdf, 0xc0, 0x4c,
-	0xd3, 0xe1, 0xe2, 0x74, 0x4f, 0x2d, 0x81, 0x3a, 0x5c, 0x10, 0xa4, 0x01, 0xc5, 0xb7, 0x52, 0x5c,
-	0x08, 0x21, 0x25, 0x86, 0x24, 0x36, 0x70, 0x64, 0x19, 0x03, 0x02, 0x00, 0x00, 0xff, 0xff, 0x90,
-	0x4d, 0xeb, 0xc0, 0xba, 0x01, 0x00, 0x00,
+	// 554 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x54, 0xc1, 0x6e, 0xd3, 0x40,
+	0x10, 0x8d, 0xe3, 0xd8, 0x21, 0x13, 0xda, 0xc2, 0xb6, 0x02, 0x2b, 0xa7, 0xb2, 0xe2, 0x50, 0x09,
+	0x08, 0x28, 0x48, 0x08, 0x09, 0x71, 0x80, 0x54, 0x0a, 0x95, 0x52, 0x51, 0x9c, 0xa8, 0x07, 0x6e,
+	0x4e, 0x3c, 0x0a, 0x56, 0x1c, 0xdb, 0xd8, 0x9b, 0x50, 0x71, 0xe2, 0xce, 0x2f, 0xf0, 0x15, 0x7c,
+	0x21, 0xda, 0xd9, 0x5d, 0xdb, 0x85, 0x44, 0xad, 0xb8, 0xcd, 0x7b, 0xf6, 0xcc, 0xbc, 0x79, 0x3b,
+	0xbb, 0x00, 0x8b, 0x30, 0xb8, 0xea, 0x67, 0x79, 0x2a, 0x52, 0xd6, 0xcc, 0x66, 0xfc, 0x87, 0x05,
+	0x6c, 0x92, 0xe5, 0x18, 0x84, 0x17, 0x79, 0x34, 0x47, 0x1f, 0xbf, 0xae, 0xb1, 0x10, 0xec, 0x01,
+	0xb8, 0xe7, 0x41, 0xbe, 0x44, 0xe1, 0x59, 0xc7, 0xd6, 0x49, 0xc7, 0xd7, 0x88, 0x31, 0x68, 0x4d,
+	0xa2, 0x10, 0xbd, 0x26, 0xb1, 0x14, 0xb3, 0x23, 0x70, 0x4e, 0x31, 0x13, 0x5f, 0x3c, 0x9b, 0x48,
+	0x05, 0x24, 0x7b, 0x89, 0xc9, 0x1a, 0xbd, 0x96, 0x62, 0x09, 0xc8, 0xfc, 0xf3, 0x34, 0x44, 0xcf,
+	0x51, 0xf9, 0x32, 0xe6, 0x0b, 0xe8, 0xd6, 0x14, 0xec, 0x6c, 0x7d, 0x04, 0x0e, 0xfd, 0xa0, 0x7b,
+	0x2b, 0xa0, 0x04, 0x7d, 0x47, 0xdd, 0x9b, 0x62, 0x59, 0x61, 0x8c, 0x1b, 0x8c, 0x0b, 0xea, 0xed,
+	0xf8, 0x1a, 0xf1, 0xb7, 0xb0, 0x37, 0x8d, 0xe6, 0x4b, 0xcc, 0x6f, 0x9a, 0xb2, 0xd4, 0xde, 0xac,
+	0x69, 0xe7, 0x17, 0xe0, 0xaa, 0xf4, 0x9d, 0x79, 0xc4, 0x5f, 0xbd, 0x8f, 0x42, 0x9d, 0xa8, 0x11,
+	0xf1, 0x51, 0xf2, 0xae, 0x58, 0x6a, 0x99, 0x1a, 0xf1, 0x9f, 0x16, 0x38, 0xd3, 0x3c, 0x08, 0x77,
+	0x0f, 0xed, 0x41, 0x9b, 0x7e, 0x38, 0x3b, 0xa5, 0x92, 0xb6, 0x6f, 0x60, 0x65, 0x87, 0xbd, 0xcd,
+	0x8e, 0x56, 0xcd, 0x0e, 0x73, 0x66, 0x4e, 0xed, 0xcc, 0x18, 0xb4, 0xa6, 0xd1, 0x0a, 0x3d, 0x97,
+	0x8a, 0x52, 0xcc, 0x87, 0x70, 0xe8, 0xe3, 0x1c, 0x13, 0x41, 0x2d, 0x8a, 0x5b, 0x98, 0x34, 0x8e,
+	0x56, 0x91, 0x20, 0x61, 0x8e, 0xaf, 0x00, 0x7f, 0x02, 0xae, 0x4a, 0x67, 0x8f, 0x4c, 0xe4, 0x59,
+	0xc7, 0xf6, 0x49, 0x77, 0xd0, 0xe9, 0x67, 0xb3, 0x3e, 0x31, 0xbe, 0xfe, 0xc0, 0x3f, 0xc3, 0xfe,
+	0x30, 0x48, 0xc2, 0xf8, 0xe6, 0x66, 0x3d, 0xb8, 0x73, 0x96, 0x08, 0xcc, 0x37, 0x41, 0xac, 0xbd,
+	0x2d, 0x71, 0x25, 0xc4, 0xae, 0x0b, 0xf9, 0x65, 0x81, 0xab, 0x8a, 0x97, 0xc3, 0x5a, 0xd5, 0xb0,
+	0x92, 0xfb, 0x98, 0x61, 0x62, 0x16, 0x59, 0xc6, 0x92, 0xfb, 0x10, 0x2d, 0xcc, 0x1e, 0x53, 0xcc,
+	0xee, 0x81, 0x3d, 0x4e, 0xbf, 0x69, 0x3f, 0x65, 0x28, 0xdb, 0x0d, 0xe3, 0xb4, 0x30, 0x7e, 0x2a,
+	0x20, 0x85, 0x5f, 0xa6, 0xf1, 0x5a, 0x5b, 0xda, 0xf1, 0x35, 0x92, 0xbc, 0x76, 0xa1, 0xad, 0x76,
+	0x51, 0x8f, 0x3e, 0x87, 0xb6, 0x1e, 0xfd, 0xbf, 0x66, 0x7e, 0x5c, 0xa6, 0x7b, 0x36, 0xb9, 0x0b,
+	0xd2, 0x5d, 0x45, 0xf9, 0xe6, 0x13, 0x7f, 0x06, 0x87, 0x13, 0x91, 0x63, 0xb0, 0xba, 0xd5, 0x89,
+	0x0e, 0x3e, 0x5d, 0x7b, 0x0a, 0x26, 0x98, 0x6f, 0xe4, 0x4a, 0xbd, 0x81, 0xfd, 0x11, 0x8a, 0x6b,
+	0x37, 0x54, 0xf6, 0xfa, 0xf7, 0xd1, 0xe8, 0x1d, 0xfc, 0xc5, 0xf3, 0xc6, 0xa0, 0xbc, 0x72, 0xa6,
+	0xda, 0x53, 0xe8, 0x8c, 0x50, 0x28, 0x8e, 0xdd, 0xa7, 0x95, 0xa8, 0x5f, 0xc9, 0x1e, 0x54, 0x14,
+	0x6f, 0x0c, 0x7e, 0x5b, 0xb0, 0xa7, 0xb4, 0x9b, 0xfc, 0xd7, 0x70, 0x30, 0x42, 0x51, 0xdf, 0x53,
+	0xf6, 0x50, 0xa6, 0x6c, 0xd9, 0x5c, 0x5d, 0x4b, 0xf9, 0xdd, 0x60, 0xcf, 0x01, 0x46, 0x28, 0x8c,
+	0xe9, 0xac, 0xf2, 0xab, 0xfc, 0xbf, 0x5b, 0xe3, 0x78, 0x83, 0xbd, 0x82, 0xbb, 0x75, 0xf7, 0x54,
+	0x9f, 0x2d, 0x7e, 0xf6, 0xaa, 0xcd, 0xe6, 0x8d, 0x17, 0xd6, 0xcc, 0xa5, 0xd7, 0xf5, 0xe5, 0x9f,
+	0x01, 0x00, 0x94, 0xfb, 0x69, 0x81, 0x6b, 0x05, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -399,3 +871,175 @@ var _TickerService_serviceDesc = grpc.ServiceDesc{
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "gdax.proto",
 }
+
+// TradesServiceClient is the client API for TradesService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type TradesServiceClient interface {
+	GetRecentTrades(ctx context.Context, in *RecentTradesRequest, opts ...grpc.CallOption) (*Trades, error)
+	GetCandles(ctx context.Context, in *CandlesRequest, opts ...grpc.CallOption) (*Candles, error)
+	StreamTrades(ctx context.Context, in *StreamTradesRequest, opts ...grpc.CallOption) (TradesService_StreamTradesClient, error)
+}
+
+type tradesServiceClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewTradesServiceClient(cc *grpc.ClientConn) TradesServiceClient {
+	return &tradesServiceClient{cc}
+}
+
+func (c *tradesServiceClient) GetRecentTrades(ctx context.Context, in *RecentTradesRequest, opts ...grpc.CallOption) (*Trades, error) {
+	out := new(Trades)
+	err := c.cc.Invoke(ctx, "/pb.TradesService/GetRecentTrades", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tradesServiceClient) GetCandles(ctx context.Context, in *CandlesRequest, opts ...grpc.CallOption) (*Candles, error) {
+	out := new(Candles)
+	err := c.cc.Invoke(ctx, "/pb.TradesService/GetCandles", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tradesServiceClient) StreamTrades(ctx context.Context, in *StreamTradesRequest, opts ...grpc.CallOption) (TradesService_StreamTradesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_TradesService_serviceDesc.Streams[0], "/pb.TradesService/StreamTrades", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &tradesServiceStreamTradesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type TradesService_StreamTradesClient interface {
+	Recv() (*Trade, error)
+	grpc.ClientStream
+}
+
+type tradesServiceStreamTradesClient struct {
+	grpc.ClientStream
+}
+
+func (x *tradesServiceStreamTradesClient) Recv() (*Trade, error) {
+	m := new(Trade)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// TradesServiceServer is the server API for TradesService service.
+type TradesServiceServer interface {
+	GetRecentTrades(context.Context, *RecentTradesRequest) (*Trades, error)
+	GetCandles(context.Context, *CandlesRequest) (*Candles, error)
+	StreamTrades(*StreamTradesRequest, TradesService_StreamTradesServer) error
+}
+
+// UnimplementedTradesServiceServer can be embedded to have forward compatible implementations.
+type UnimplementedTradesServiceServer struct {
+}
+
+func (*UnimplementedTradesServiceServer) GetRecentTrades(ctx context.Context, req *RecentTradesRequest) (*Trades, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRecentTrades not implemented")
+}
+func (*UnimplementedTradesServiceServer) GetCandles(ctx context.Context, req *CandlesRequest) (*Candles, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCandles not implemented")
+}
+func (*UnimplementedTradesServiceServer) StreamTrades(req *StreamTradesRequest, srv TradesService_StreamTradesServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamTrades not implemented")
+}
+
+func RegisterTradesServiceServer(s *grpc.Server, srv TradesServiceServer) {
+	s.RegisterService(&_TradesService_serviceDesc, srv)
+}
+
+func _TradesService_GetRecentTrades_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RecentTradesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TradesServiceServer).GetRecentTrades(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.TradesService/GetRecentTrades",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TradesServiceServer).GetRecentTrades(ctx, req.(*RecentTradesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TradesService_GetCandles_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CandlesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TradesServiceServer).GetCandles(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.TradesService/GetCandles",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TradesServiceServer).GetCandles(ctx, req.(*CandlesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TradesService_StreamTrades_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamTradesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(TradesServiceServer).StreamTrades(m, &tradesServiceStreamTradesServer{stream})
+}
+
+type TradesService_StreamTradesServer interface {
+	Send(*Trade) error
+	grpc.ServerStream
+}
+
+type tradesServiceStreamTradesServer struct {
+	grpc.ServerStream
+}
+
+func (x *tradesServiceStreamTradesServer) Send(m *Trade) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+var _TradesService_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "pb.TradesService",
+	HandlerType: (*TradesServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetRecentTrades",
+			Handler:    _TradesService_GetRecentTrades_Handler,
+		},
+		{
+			MethodName: "GetCandles",
+			Handler:    _TradesService_GetCandles_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamTrades",
+			Handler:       _TradesService_StreamTrades_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "gdax.proto",
+}
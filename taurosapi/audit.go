@@ -0,0 +1,136 @@
+package taurosapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// AuditRecord - an order placement, cancellation or withdrawal request to Tauros and its response, with secrets redacted
+type AuditRecord struct {
+	Time     time.Time       `json:"time"`
+	Method   string          `json:"method"`
+	Endpoint string          `json:"endpoint"`
+	Request  json.RawMessage `json:"request,omitempty"`
+	Status   int             `json:"status"`
+	Response json.RawMessage `json:"response,omitempty"`
+	Latency  float64         `json:"latency_ms"`
+	OrderID  int64           `json:"order_id,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// AuditHook - receives a record of every audited request
+type AuditHook func(AuditRecord)
+
+var auditHook struct {
+	sync.RWMutex
+	hook AuditHook
+}
+
+// fields of requests and responses that are never logged or audited
+var secretFields = map[string]bool{"password": true, "token": true, "code": true, "api_key": true, "secret": true}
+
+// SetAuditHook - send a record of every order placement, cancellation and withdrawal to hook, nil stops auditing
+func SetAuditHook(hook AuditHook) {
+	auditHook.Lock()
+	auditHook.hook = hook
+	auditHook.Unlock()
+}
+
+// auditedServices - the requests that change the orders or move funds, the public market data is not audited
+var auditedServices = []string{"trading/placeorder", "trading/closeorder", "data/withdraw"}
+
+func audited(tauService string) bool {
+	for _, s := range auditedServices {
+		if strings.HasPrefix(tauService, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func audit(r AuditRecord) {
+	auditHook.RLock()
+	hook := auditHook.hook
+	auditHook.RUnlock()
+	if hook != nil {
+		hook(r)
+	}
+}
+
+// AuditLog - append only json lines file of audit records
+type AuditLog struct {
+	mux  sync.Mutex
+	file *os.File
+}
+
+// OpenAuditLog - open or create the audit log file, records are always appended
+func OpenAuditLog(filename string) (*AuditLog, error) {
+	f, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("OpenAuditLog-> %v", err)
+	}
+	return &AuditLog{file: f}, nil
+}
+
+// Record - append r to the audit log, it is meant to be used as the AuditHook
+func (a *AuditLog) Record(r AuditRecord) {
+	line, err := json.Marshal(r)
+	if err != nil {
+		log.Errorf("tauapi: unable to marshal audit record: %v", err)
+		return
+	}
+	a.mux.Lock()
+	defer a.mux.Unlock()
+	if _, err := a.file.Write(append(line, '\n')); err != nil {
+		log.Errorf("tauapi: unable to write audit record: %v", err)
+	}
+}
+
+// Close - close the audit log file
+func (a *AuditLog) Close() error {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+	return a.file.Close()
+}
+
+// redact returns data with the values of secret fields replaced, data that is not json is returned
+// as a json string
+func redact(data []byte) json.RawMessage {
+	if len(data) == 0 {
+		return nil
+	}
+	var v interface{}
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber() // keep amounts exactly as they were
+	if err := d.Decode(&v); err != nil {
+		s, _ := json.Marshal(string(data))
+		return s
+	}
+	r, _ := json.Marshal(redactValue(v))
+	return r
+}
+
+func redactValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, e := range t {
+			if secretFields[strings.ToLower(k)] {
+				t[k] = "[REDACTED]"
+			} else {
+				t[k] = redactValue(e)
+			}
+		}
+	case []interface{}:
+		for i, e := range t {
+			t[i] = redactValue(e)
+		}
+	}
+	return v
+}
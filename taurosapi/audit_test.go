@@ -0,0 +1,25 @@
+package taurosapi
+
+import "testing"
+
+func TestAudited(t *testing.T) {
+	tests := []struct {
+		service string
+		want    bool
+	}{
+		{"trading/placeorder/", true},
+		{"trading/closeorder/", true},
+		{"data/withdraw/", true},
+		{"trading/orderbook/?market=btc-mxn", false},
+		{"trading/trades/?market=btc-mxn", false},
+		{"trading/myopenorders/", false},
+		{"trading/getorder/?id=1", false},
+		{"data/listbalances", false},
+		{"auth/signin/", false},
+	}
+	for _, tt := range tests {
+		if got := audited(tt.service); got != tt.want {
+			t.Errorf("audited(%q) = %v, want %v", tt.service, got, tt.want)
+		}
+	}
+}
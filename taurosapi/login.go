@@ -0,0 +1,103 @@
+package taurosapi
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// LoginConfig - credentials used by LoginWith, TOTPSecret is the base32 secret of the account second
+// factor and is only needed if the account has two factor authentication enabled
+type LoginConfig struct {
+	Email      string
+	Password   string
+	TOTPSecret string
+	DeviceName string
+	DeviceID   string
+}
+
+// ErrTwoFactorRequired - the account has two factor authentication enabled and no TOTP secret was given
+var ErrTwoFactorRequired = errors.New("two factor code required but no totp secret configured")
+
+// LoginWith - log in with config to get a jwt token, answering the second factor challenge with
+// the code of config.TOTPSecret if the account has it enabled
+func LoginWith(config LoginConfig) (jwtToken string, err error) {
+	if jwtToken, err = login(config); err != nil {
+		return "", fmt.Errorf("LoginWith->%v", err)
+	}
+	return jwtToken, nil
+}
+
+// login signs in with config, answering the second factor challenge if the account has it enabled
+func login(config LoginConfig) (string, error) {
+	if config.DeviceName == "" {
+		config.DeviceName = "Bot"
+	}
+	m := Message{
+		Email:      config.Email,
+		Password:   config.Password,
+		DeviceName: config.DeviceName,
+		DeviceID:   config.DeviceID,
+	}
+	d, err := authRequest("auth/signin/", &m, "")
+	if err != nil {
+		return "", fmt.Errorf("login-> %v", err)
+	}
+	if !d.TwoFactor {
+		return d.Token, nil
+	}
+	if config.TOTPSecret == "" {
+		return "", ErrTwoFactorRequired
+	}
+	code, err := totpCode(config.TOTPSecret, time.Now())
+	if err != nil {
+		return "", fmt.Errorf("login-> %v", err)
+	}
+	m.Code = code
+	if d, err = authRequest("auth/verify-tfa/", &m, "JWT "+d.Token); err != nil {
+		return "", fmt.Errorf("login two factor-> %v", err)
+	}
+	return d.Token, nil
+}
+
+type authResponse struct {
+	Token     string `json:"token"`
+	TwoFactor bool   `json:"two_factor"`
+}
+
+func authRequest(tauService string, m *Message, authorization string) (d authResponse, err error) {
+	jsonData, err := doTauRequestAuth(2, "POST", tauService, m, authorization)
+	if err != nil {
+		return d, err
+	}
+	if err := json.Unmarshal(jsonData, &d); err != nil {
+		return d, err
+	}
+	if d.Token == "" {
+		return d, errors.New("no token in response")
+	}
+	return d, nil
+}
+
+// totpCode generates the RFC 6238 time based one time password of secret, 6 digits every 30 seconds
+func totpCode(secret string, t time.Time) (string, error) {
+	secret = strings.ToUpper(strings.Replace(secret, " ", "", -1))
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.TrimRight(secret, "="))
+	if err != nil {
+		return "", fmt.Errorf("invalid totp secret: %v", err)
+	}
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(t.Unix()/30))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	code := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%06d", code%1000000), nil
+}
@@ -0,0 +1,27 @@
+package taurosapi_test
+
+import (
+	"strings"
+	"testing"
+
+	tau "git.vmo.mx/Tauros/tradingbot/taurosapi"
+	"git.vmo.mx/Tauros/tradingbot/taurosapi/taurosfake"
+)
+
+func TestLoginWithTwoFactor(t *testing.T) {
+	fake := taurosfake.New("test-token")
+	defer fake.Close()
+	tau.SetURL(fake.URL)
+	fake.SetCredentials("bot@tauros.io", "secret", true)
+
+	if _, err := tau.Login("bot@tauros.io", "secret"); err == nil || !strings.Contains(err.Error(), tau.ErrTwoFactorRequired.Error()) {
+		t.Errorf("Login of a two factor account = %v, want %v", err, tau.ErrTwoFactorRequired)
+	}
+	token, err := tau.LoginWith(tau.LoginConfig{Email: "bot@tauros.io", Password: "secret", TOTPSecret: "JBSWY3DPEHPK3PXP"})
+	if err != nil || token == "" {
+		t.Fatalf("LoginWith a totp secret = %q, %v", token, err)
+	}
+	if _, err := tau.LoginWith(tau.LoginConfig{Email: "bot@tauros.io", Password: "wrong"}); err == nil {
+		t.Errorf("LoginWith a wrong password did not fail")
+	}
+}
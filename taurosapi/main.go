@@ -9,30 +9,31 @@ import (
 	"time"
 	"strings"
 
+	"github.com/shopspring/decimal"
 	log "github.com/sirupsen/logrus"
 )
 
 // TauWsObject - Tauros Websocket message "object"
 type TauWsObject struct {
-	Amount         string `json:"amount"`
-	AmountPaid     string `json:"amount_paid"`
-	AmountReceived string `json:"amount_received"`
-	ClosedAt       string `json:"closed_at"`
-	CreatedAt      string `json:"created_at"`
-	FeeAmountPaid  string `json:"fee_amount_paid"`
-	FeeDecimal     string `json:"fee_decimal"`
-	FeePercent     string `json:"fee_percent"`
-	Filled         string `json:"filled"`
-	ID             int64  `json:"id"`
-	InitialAmount  string `json:"initial_amount"`
-	InitialValue   string `json:"initial_value"`
-	IsOpen         bool   `json:"is_open"`
-	LeftCoin       string `json:"left_coin"`
-	Market         string `json:"market"`
-	Price          string `json:"price"`
-	RightCoin      string `json:"right_coin"`
-	Side           string `json:"side"`
-	Value          string `json:"value"`
+	Amount         decimal.Decimal `json:"amount"`
+	AmountPaid     decimal.Decimal `json:"amount_paid"`
+	AmountReceived decimal.Decimal `json:"amount_received"`
+	ClosedAt       string          `json:"closed_at"`
+	CreatedAt      string          `json:"created_at"`
+	FeeAmountPaid  decimal.Decimal `json:"fee_amount_paid"`
+	FeeDecimal     decimal.Decimal `json:"fee_decimal"`
+	FeePercent     decimal.Decimal `json:"fee_percent"`
+	Filled         decimal.Decimal `json:"filled"`
+	ID             int64           `json:"id"`
+	InitialAmount  decimal.Decimal `json:"initial_amount"`
+	InitialValue   decimal.Decimal `json:"initial_value"`
+	IsOpen         bool            `json:"is_open"`
+	LeftCoin       string          `json:"left_coin"`
+	Market         string          `json:"market"`
+	Price          decimal.Decimal `json:"price"`
+	RightCoin      string          `json:"right_coin"`
+	Side           string          `json:"side"`
+	Value          decimal.Decimal `json:"value"`
 }
 
 // TauWsMessage - Tauros Websocket message header
@@ -44,38 +45,46 @@ type TauWsMessage struct {
 	Object      TauWsObject `json:"object"`
 }
 
-// Message - main message struct
+// Message - main message struct, amounts and prices are sent as json strings and left out when nil
 type Message struct {
-	ID       int64  `json:"id"`
-	Market   string `json:"market"`
-	Amount   string `json:"amount"`
-	Side     string `json:"side"`
-	Type     string `json:"type"`
-	Price    string `json:"price"`
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	ID       int64            `json:"id"`
+	Market   string           `json:"market"`
+	Amount   *decimal.Decimal `json:"amount,omitempty"`
+	Side     string           `json:"side"`
+	Type     string           `json:"type"`
+	Price    *decimal.Decimal `json:"price,omitempty"`
+	Email    string           `json:"email"`
+	Password string           `json:"password"`
+	// used only by the wallet endpoints
+	Coin    string `json:"coin,omitempty"`
+	Address string `json:"address,omitempty"`
+	// used only by the auth endpoints
+	DeviceName string `json:"device_name,omitempty"`
+	DeviceID   string `json:"unique_device_id,omitempty"`
+	Code       string `json:"code,omitempty"`
+	Token      string `json:"token,omitempty"`
 }
 
 // Order - order message struct
 type Order struct {
-	ID            int64       `json:"order_id"`
-	Market        string      `json:"market"`
-	Side          string      `json:"side"`
-	Amount        json.Number `json:"amount,Number"`
-	InitialAmount json.Number `json:"initial_amount,Number"`
-	Filled        json.Number `json:"filled,Number"`
-	Value         json.Number `json:"value,Number"`
-	InitialValue  json.Number `json:"initial_value,Number"`
-	Price         json.Number `json:"price,Number"`
-	CreatedAt     string      `json:"created_at"`
+	ID            int64           `json:"order_id"`
+	Market        string          `json:"market"`
+	Side          string          `json:"side"`
+	Amount        decimal.Decimal `json:"amount"`
+	InitialAmount decimal.Decimal `json:"initial_amount"`
+	Filled        decimal.Decimal `json:"filled"`
+	Value         decimal.Decimal `json:"value"`
+	InitialValue  decimal.Decimal `json:"initial_value"`
+	Price         decimal.Decimal `json:"price"`
+	CreatedAt     string          `json:"created_at"`
 }
 
 // Coin - available coins
 type Coin struct {
-	Coin                  string      `json:"coin"`
-	MinWithdrawal         json.Number `json:"min_withdraw"`
-	FeeWithdrawal         json.Number `json:"fee_withdraw"`
-	ConfirmationsRequired int         `json:"confirmations_required"`
+	Coin                  string          `json:"coin"`
+	MinWithdrawal         decimal.Decimal `json:"min_withdraw"`
+	FeeWithdrawal         decimal.Decimal `json:"fee_withdraw"`
+	ConfirmationsRequired int             `json:"confirmations_required"`
 }
 
 // Balance - available balances
@@ -84,9 +93,9 @@ type Balance struct {
 	CoinName string `json:"coin_name"`
 	Address  string `json:"address"`
 	Balances struct {
-		Available json.Number `json:"available"`
-		Pending   json.Number `json:"pending"`
-		Frozen    json.Number `json:"frozen"`
+		Available decimal.Decimal `json:"available"`
+		Pending   decimal.Decimal `json:"pending"`
+		Frozen    decimal.Decimal `json:"frozen"`
 	} `json:"balances"`
 }
 
@@ -162,7 +171,11 @@ func PlaceOrder(order Message) (orderID int64, error error) {
 
 // GetOpenOrders - get all open orders by the user
 func GetOpenOrders() (orders []Order, error error) {
-	jsonData, err := doTauRequest(1, "GET", "trading/myopenorders/", nil)
+	return getOpenOrders("Token " + apiToken)
+}
+
+func getOpenOrders(authorization string) (orders []Order, error error) {
+	jsonData, err := doTauRequestAuth(1, "GET", "trading/myopenorders/", nil, authorization)
 	if err != nil {
 		return nil, fmt.Errorf("GetOpenOrders->%v", err)
 	}
@@ -173,86 +186,124 @@ func GetOpenOrders() (orders []Order, error error) {
 	return orders, nil
 }
 
-// CloseAllOrders - close all currently open orders
+// CloseAllOrders - close all currently open orders of every market
 func CloseAllOrders() error {
 	log.Info("closing all orders...")
-	orders, err := GetOpenOrders()
+	results, err := CancelOrders(CancelFilter{})
 	if err != nil {
 		return fmt.Errorf("CloseAllOrders ->%v", err)
 	}
-	for _, o := range orders {
-		if err := CloseOrder(o.ID); err != nil {
-			return fmt.Errorf("CloseAllOrders Deleting Order %d ->%v", o.ID, err)
+	failed := 0
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+			err = r.Err
 		}
 	}
+	if failed > 0 {
+		return fmt.Errorf("CloseAllOrders -> unable to close %d of %d orders, last error: %v", failed, len(results), err)
+	}
 	return nil
 }
 
 // CloseOrder - close the order specified by the order ID
 func CloseOrder(orderID int64) error {
+	return closeOrder(orderID, "Token "+apiToken)
+}
+
+func closeOrder(orderID int64, authorization string) error {
 	var m Message
 	m.ID = orderID
 	log.Tracef("tauapi: del Order %d", orderID)
-	_, err := doTauRequest(1, "POST", "trading/closeorder/", &m)
+	_, err := doTauRequestAuth(1, "POST", "trading/closeorder/", &m, authorization)
 	if err != nil {
 		return fmt.Errorf("CloseOrder->%v", err)
 	}
 	return nil
 }
 
-// Login - simulate a login to get the jwt token
+// Login - simulate a login to get the jwt token, use LoginWith for accounts with two factor authentication
 func Login(email string, password string) (jwtToken string, err error) {
-	var m Message
-	m.Email = email
-	m.Password = password
-	jsonData, err := doTauRequest(2, "POST", "auth/signin/", &m)
-	if err != nil {
+	if jwtToken, err = login(LoginConfig{Email: email, Password: password}); err != nil {
 		return "", fmt.Errorf("Login->%v", err)
 	}
-	var d struct {
-		Token     string `json:"token"`
-		TwoFactor bool   `json:"two_factor"`
-	}
-	if err := json.Unmarshal(jsonData, &d); err != nil {
-		return "", fmt.Errorf("Login->%v", err)
-	}
-	return d.Token, nil
+	return jwtToken, nil
 }
 
 func doTauRequest(version int, reqType string, tauService string, message *Message) (msgdata json.RawMessage, error error) {
-	jsonMsg, err := json.Marshal(message)
-	if err != nil {
-		return nil, fmt.Errorf("doTauRequest-> Error trying to json marshal tauMessage: %v", err)
-	}
-	log.Tracef("reqType: [%s], tauService: [%s] message: %+v", reqType, tauService, jsonMsg)
-	var httpReq *http.Request
+	return doTauRequestAuth(version, reqType, tauService, message, "Token "+apiToken)
+}
+
+// doTauRequestAuth sends the request with authorization as the Authorization header, or without one if empty
+func doTauRequestAuth(version int, reqType string, tauService string, message *Message, authorization string) (json.RawMessage, error) {
+	var msgdata json.RawMessage
 	var b []byte
+	var err error
 	if reqType != "GET" {
 		if b, err = json.Marshal(message); err != nil {
 			return nil, fmt.Errorf("doTauRequest-> Error on body marshal: %v", err)
 		}
 	}
-	log.Tracef("url=%s token=%s", apiURL+"/api/v1/"+tauService, apiToken)
-	apiVersion := fmt.Sprintf("v%1d", version)
-	httpReq, err = http.NewRequest(reqType, apiURL+"/api/"+apiVersion+"/"+tauService, bytes.NewBuffer(b))
+	log.Tracef("reqType: [%s], tauService: [%s] message: %s", reqType, tauService, redact(b))
+	start := time.Now()
+	status, body, err := sendTauRequest(version, reqType, tauService, b, authorization)
+	if err == nil {
+		msgdata, err = parseTauResponse(version, status, body)
+	}
+	if audited(tauService) {
+		r := AuditRecord{
+			Time:     start.UTC(),
+			Method:   reqType,
+			Endpoint: fmt.Sprintf("v%d/%s", version, tauService),
+			Request:  redact(b),
+			Status:   status,
+			Response: redact(body),
+			Latency:  float64(time.Since(start)/time.Microsecond) / 1000,
+		}
+		if message != nil && message.ID != 0 {
+			r.OrderID = message.ID
+		} else if err == nil && strings.HasPrefix(tauService, "trading/placeorder") {
+			var d struct {
+				ID int64 `json:"id"`
+			}
+			json.Unmarshal(msgdata, &d)
+			r.OrderID = d.ID
+		}
+		if err != nil {
+			r.Error = err.Error()
+		}
+		audit(r)
+	}
+	return msgdata, err
+}
+
+func sendTauRequest(version int, reqType string, tauService string, b []byte, authorization string) (status int, body []byte, error error) {
+	url := fmt.Sprintf("%s/api/v%1d/%s", apiURL, version, tauService)
+	log.Tracef("url=%s", url)
+	httpReq, err := http.NewRequest(reqType, url, bytes.NewBuffer(b))
 	if err != nil {
-		return nil, fmt.Errorf("doTauRequest-> Error on http.NewRequest: %v", err)
+		return 0, nil, fmt.Errorf("doTauRequest-> Error on http.NewRequest: %v", err)
 	}
 	httpReq.Header.Set("Content-Type", "application/json")
 	httpReq.Header.Set("Accept", "application/json")
-	httpReq.Header.Set("Authorization", "Token "+apiToken)
+	if authorization != "" {
+		httpReq.Header.Set("Authorization", authorization)
+	}
 	client := http.Client{Timeout: time.Second * 10}
 	resp, err := client.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("doTauRequest-> Error reading response: %v", err)
+		return 0, nil, fmt.Errorf("doTauRequest-> Error reading response: %v", err)
 	}
 	defer resp.Body.Close()
-	//todo: check StatusCode
-	body, err := ioutil.ReadAll(resp.Body)
-	log.Tracef("resp body=%s", string(body))
+	body, err = ioutil.ReadAll(resp.Body)
+	log.Tracef("resp status=%d body=%s", resp.StatusCode, redact(body))
 	if err != nil {
-		return nil, fmt.Errorf("doTauRequest-> Error ioutil body: %v", err)
+		return resp.StatusCode, nil, fmt.Errorf("doTauRequest-> Error ioutil body: %v", err)
 	}
+	return resp.StatusCode, body, nil
+}
+
+func parseTauResponse(version int, status int, body []byte) (msgdata json.RawMessage, error error) {
 	var respJSON struct {
 		Success bool            `json:"success"`
 		Message json.RawMessage `json:"msg"`
@@ -260,22 +311,19 @@ func doTauRequest(version int, reqType string, tauService string, message *Messa
 		Payload json.RawMessage `json:"payload"`
 	}
 	if err := json.Unmarshal(body, &respJSON); err != nil {
-		return nil, fmt.Errorf("doTauRequest-> Unmarshall error: %v", err)
+		return nil, fmt.Errorf("doTauRequest-> Unmarshall error, http status %d: %v", status, err)
 	}
 	if !respJSON.Success {
 		msg := string(respJSON.Message)
-		if msg=="" {
-			msg=string(body)
-		}
-		if strings.Contains(msg, "Invalid token") {
-			msg+=" Token="+apiToken
+		if msg == "" {
+			msg = string(redact(body))
 		}
 		return nil, fmt.Errorf("doTauRequest-> Unsuccess message %s", msg)
 	}
 	if version == 1 {
-		return respJSON.Data, err
+		return respJSON.Data, nil
 	}
-	return respJSON.Payload, err
+	return respJSON.Payload, nil
 }
 
 //Init start the tauros api
@@ -285,3 +333,8 @@ func Init(testing bool, token string) {
 	}
 	apiToken = token
 }
+
+//SetURL use another base url for the tauros api, like a taurosfake server
+func SetURL(url string) {
+	apiURL = strings.TrimRight(url, "/")
+}
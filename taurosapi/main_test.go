@@ -0,0 +1,75 @@
+package taurosapi
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// recordBodies - start a Tauros api that answers every request successfully and records the request
+// bodies by service
+func recordBodies(t *testing.T) (*httptest.Server, map[string]string) {
+	var mux sync.Mutex
+	bodies := make(map[string]string)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		service := strings.Trim(strings.SplitN(r.URL.Path, "/", 4)[3], "/")
+		mux.Lock()
+		bodies[service] = string(b)
+		mux.Unlock()
+		switch service {
+		case "auth/signin":
+			w.Write([]byte(`{"success": true, "payload": {"token": "jwt"}}`))
+		case "data/coins":
+			w.Write([]byte(`{"success": true, "data": {"crypto": [{"coin": "BTC", "min_withdraw": "0.001"}]}}`))
+		default:
+			w.Write([]byte(`{"success": true, "data": {"id": 7}}`))
+		}
+	}))
+	apiURL, apiToken = srv.URL, "test-token"
+	return srv, bodies
+}
+
+func TestRequestBodies(t *testing.T) {
+	srv, bodies := recordBodies(t)
+	defer srv.Close()
+	SetWithdrawalWhitelist(map[string][]string{"btc": {"bc1qwhitelisted"}})
+	defer SetWithdrawalWhitelist(nil)
+	amount, price := decimal.New(5, -1), decimal.New(200001, -2)
+
+	tests := []struct {
+		service string
+		call    func() error
+		want    string
+	}{
+		{"trading/placeorder", func() error {
+			_, err := PlaceOrder(Message{Market: "btc-mxn", Side: "buy", Type: "limit", Amount: &amount, Price: &price})
+			return err
+		}, `{"id":0,"market":"btc-mxn","amount":"0.5","side":"buy","type":"limit","price":"2000.01","email":"","password":""}`},
+		{"trading/closeorder", func() error {
+			return CloseOrder(7)
+		}, `{"id":7,"market":"","side":"","type":"","email":"","password":""}`},
+		{"data/withdraw", func() error {
+			_, err := Withdraw("btc", "bc1qwhitelisted", amount)
+			return err
+		}, `{"id":0,"market":"","amount":"0.5","side":"","type":"","email":"","password":"","coin":"btc","address":"bc1qwhitelisted"}`},
+		{"auth/signin", func() error {
+			_, err := Login("bot@tauros.io", "secret")
+			return err
+		}, `{"id":0,"market":"","side":"","type":"","email":"bot@tauros.io","password":"secret","device_name":"Bot"}`},
+	}
+	for _, tt := range tests {
+		if err := tt.call(); err != nil {
+			t.Errorf("%s: %v", tt.service, err)
+			continue
+		}
+		if got := bodies[tt.service]; got != tt.want {
+			t.Errorf("%s body = %s, want %s", tt.service, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package taurosapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// BookEntry - price level of the Tauros order book
+type BookEntry struct {
+	Price  decimal.Decimal `json:"price"`
+	Amount decimal.Decimal `json:"amount"`
+	Value  decimal.Decimal `json:"value"`
+}
+
+// OrderBook - public order book of a market, bids sorted from highest and asks from lowest price
+type OrderBook struct {
+	Bids []BookEntry `json:"bids"`
+	Asks []BookEntry `json:"asks"`
+}
+
+// Ticker - public 24 hour summary of a market
+type Ticker struct {
+	Market    string          `json:"market"`
+	Last      decimal.Decimal `json:"last"`
+	Bid       decimal.Decimal `json:"bid"`
+	Ask       decimal.Decimal `json:"ask"`
+	High      decimal.Decimal `json:"high"`
+	Low       decimal.Decimal `json:"low"`
+	Volume    decimal.Decimal `json:"volume"`
+	CreatedAt string          `json:"date"`
+}
+
+// PublicTrade - a trade of any user in a market, side is the taker side
+type PublicTrade struct {
+	Price     decimal.Decimal `json:"price"`
+	Amount    decimal.Decimal `json:"amount"`
+	Value     decimal.Decimal `json:"value"`
+	Side      string          `json:"side"`
+	CreatedAt string          `json:"created_at"`
+}
+
+// GetOrderBook - get the public order book of a market
+func GetOrderBook(market string) (book OrderBook, error error) {
+	jsonData, err := doTauRequest(1, "GET", "trading/orderbook/?market="+market, nil)
+	if err != nil {
+		return book, fmt.Errorf("GetOrderBook-> %v", err)
+	}
+	if err := json.Unmarshal(jsonData, &book); err != nil {
+		return book, fmt.Errorf("GetOrderBook-> %v", err)
+	}
+	return book, nil
+}
+
+// GetTicker - get the public ticker of a market
+func GetTicker(market string) (ticker Ticker, error error) {
+	jsonData, err := doTauRequest(1, "GET", "data/ticker/?market="+market, nil)
+	if err != nil {
+		return ticker, fmt.Errorf("GetTicker-> %v", err)
+	}
+	if err := json.Unmarshal(jsonData, &ticker); err != nil {
+		return ticker, fmt.Errorf("GetTicker-> %v", err)
+	}
+	return ticker, nil
+}
+
+// GetRecentTrades - get the latest public trades of a market, newest first
+func GetRecentTrades(market string) (trades []PublicTrade, error error) {
+	jsonData, err := doTauRequest(1, "GET", "trading/trades/?market="+market, nil)
+	if err != nil {
+		return nil, fmt.Errorf("GetRecentTrades-> %v", err)
+	}
+	if err := json.Unmarshal(jsonData, &trades); err != nil {
+		return nil, fmt.Errorf("GetRecentTrades-> %v", err)
+	}
+	return trades, nil
+}
+
+// BestBid - highest bid in the book, ok is false if there are no bids
+func (b OrderBook) BestBid() (bid BookEntry, ok bool) {
+	if len(b.Bids) == 0 {
+		return bid, false
+	}
+	return b.Bids[0], true
+}
+
+// BestAsk - lowest ask in the book, ok is false if there are no asks
+func (b OrderBook) BestAsk() (ask BookEntry, ok bool) {
+	if len(b.Asks) == 0 {
+		return ask, false
+	}
+	return b.Asks[0], true
+}
+
+// Without - the book without the amounts of the given orders, so only other users liquidity is left
+func (b OrderBook) Without(orders []Order) OrderBook {
+	return OrderBook{
+		Bids: removeOrders(b.Bids, orders, "buy"),
+		Asks: removeOrders(b.Asks, orders, "sell"),
+	}
+}
+
+func removeOrders(entries []BookEntry, orders []Order, side string) []BookEntry {
+	var result []BookEntry
+	for _, e := range entries {
+		for _, o := range orders {
+			if strings.EqualFold(o.Side, side) && o.Price.Equal(e.Price) {
+				e.Amount = e.Amount.Sub(o.Amount)
+			}
+		}
+		if e.Amount.Sign() > 0 {
+			e.Value = e.Amount.Mul(e.Price)
+			result = append(result, e)
+		}
+	}
+	return result
+}
@@ -0,0 +1,89 @@
+package taurosapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Market - trading rules of a market
+type Market struct {
+	Name            string          `json:"name"`
+	LeftCoin        string          `json:"left_coin"`
+	RightCoin       string          `json:"right_coin"`
+	PricePrecision  int32           `json:"price_decimals"`
+	AmountPrecision int32           `json:"amount_decimals"`
+	MinAmount       decimal.Decimal `json:"min_amount"`
+	MinValue        decimal.Decimal `json:"min_value"`
+	MakerFee        decimal.Decimal `json:"maker_fee"`
+	TakerFee        decimal.Decimal `json:"taker_fee"`
+}
+
+// MarketsCacheTTL - how long the market rules are kept before asking the exchange again
+var MarketsCacheTTL = time.Hour
+
+var marketsCache struct {
+	sync.RWMutex
+	markets map[string]Market
+	updated time.Time
+}
+
+// GetMarkets - get the trading rules of all markets and refresh the markets cache
+func GetMarkets() (markets []Market, error error) {
+	var d struct {
+		Markets []Market `json:"markets"`
+	}
+	jsonData, err := doTauRequest(1, "GET", "data/listmarkets/", nil)
+	if err != nil {
+		return nil, fmt.Errorf("GetMarkets-> %v", err)
+	}
+	if err := json.Unmarshal(jsonData, &d); err != nil {
+		return nil, fmt.Errorf("GetMarkets-> %v", err)
+	}
+	marketsCache.Lock()
+	marketsCache.markets = make(map[string]Market, len(d.Markets))
+	for _, m := range d.Markets {
+		marketsCache.markets[strings.ToLower(m.Name)] = m
+	}
+	marketsCache.updated = time.Now()
+	marketsCache.Unlock()
+	return d.Markets, nil
+}
+
+// GetMarket - get the trading rules of a market, using the cache while it is fresh
+func GetMarket(market string) (Market, error) {
+	market = strings.ToLower(market)
+	marketsCache.RLock()
+	m, ok := marketsCache.markets[market]
+	fresh := time.Since(marketsCache.updated) < MarketsCacheTTL
+	marketsCache.RUnlock()
+	if ok && fresh {
+		return m, nil
+	}
+	if _, err := GetMarkets(); err != nil {
+		if ok { // better stale rules than none
+			return m, nil
+		}
+		return Market{}, fmt.Errorf("GetMarket-> %v", err)
+	}
+	marketsCache.RLock()
+	defer marketsCache.RUnlock()
+	if m, ok = marketsCache.markets[market]; !ok {
+		return Market{}, fmt.Errorf("GetMarket-> unknown market %s", market)
+	}
+	return m, nil
+}
+
+// TickSize - minimum price increment of the market
+func (m Market) TickSize() decimal.Decimal {
+	return decimal.New(1, -m.PricePrecision)
+}
+
+// LotSize - minimum amount increment of the market
+func (m Market) LotSize() decimal.Decimal {
+	return decimal.New(1, -m.AmountPrecision)
+}
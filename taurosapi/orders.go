@@ -0,0 +1,217 @@
+package taurosapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// order states as reported by OrderDetail.State
+const (
+	OrderOpen      = "open"
+	OrderFilled    = "filled"
+	OrderCancelled = "cancelled"
+)
+
+// OrderDetail - an order of the user, open or already closed
+type OrderDetail struct {
+	Order
+	Status        string          `json:"status"`
+	IsOpen        bool            `json:"is_open"`
+	FeeAmountPaid decimal.Decimal `json:"fee_amount_paid"`
+	ClosedAt      string          `json:"closed_at"`
+}
+
+// Trade - a fill of one of the user orders
+type Trade struct {
+	ID        int64           `json:"id"`
+	OrderID   int64           `json:"order_id"`
+	Market    string          `json:"market"`
+	Side      string          `json:"side"`
+	Amount    decimal.Decimal `json:"amount"`
+	Price     decimal.Decimal `json:"price"`
+	Value     decimal.Decimal `json:"value"`
+	Fee       decimal.Decimal `json:"fee_amount"`
+	FeeCoin   string          `json:"fee_coin"`
+	IsMaker   bool            `json:"is_maker"`
+	CreatedAt string          `json:"created_at"`
+}
+
+// State - open, filled or cancelled, a cancelled order may have been partially filled
+func (o OrderDetail) State() string {
+	switch {
+	case o.Status != "":
+		return o.Status
+	case o.IsOpen:
+		return OrderOpen
+	case o.Filled.GreaterThanOrEqual(o.InitialAmount) && o.Filled.Sign() > 0:
+		return OrderFilled
+	}
+	return OrderCancelled
+}
+
+// GetOrder - get the status of an order of the user, even if it is no longer open
+func GetOrder(orderID int64) (order OrderDetail, error error) {
+	jsonData, err := doTauRequest(1, "GET", "trading/getorder/?id="+strconv.FormatInt(orderID, 10), nil)
+	if err != nil {
+		return order, fmt.Errorf("GetOrder-> %v", err)
+	}
+	if err := json.Unmarshal(jsonData, &order); err != nil {
+		return order, fmt.Errorf("GetOrder-> %v", err)
+	}
+	return order, nil
+}
+
+// GetOrderHistory - get all closed orders of the user in market created after since,
+// an empty market or a zero since are not used as filters
+func GetOrderHistory(market string, since time.Time) ([]OrderDetail, error) {
+	var orders []OrderDetail
+	err := getPages("trading/myclosedorders/", historyParams(market, since), func(page json.RawMessage) error {
+		var o []OrderDetail
+		if err := json.Unmarshal(page, &o); err != nil {
+			return err
+		}
+		orders = append(orders, o...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("GetOrderHistory-> %v", err)
+	}
+	return orders, nil
+}
+
+// GetTrades - get all trades of the user in market made after since,
+// an empty market or a zero since are not used as filters
+func GetTrades(market string, since time.Time) ([]Trade, error) {
+	var trades []Trade
+	err := getPages("trading/mytrades/", historyParams(market, since), func(page json.RawMessage) error {
+		var t []Trade
+		if err := json.Unmarshal(page, &t); err != nil {
+			return err
+		}
+		trades = append(trades, t...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("GetTrades-> %v", err)
+	}
+	return trades, nil
+}
+
+func historyParams(market string, since time.Time) url.Values {
+	params := url.Values{}
+	if market != "" {
+		params.Set("market", market)
+	}
+	if !since.IsZero() {
+		params.Set("since", since.UTC().Format(time.RFC3339))
+	}
+	return params
+}
+
+// getPages walks all the pages of a paginated endpoint, calling addPage with the results of each one
+func getPages(tauService string, params url.Values, addPage func(json.RawMessage) error) error {
+	for page := 1; ; page++ {
+		params.Set("page", strconv.Itoa(page))
+		jsonData, err := doTauRequest(1, "GET", tauService+"?"+params.Encode(), nil)
+		if err != nil {
+			return err
+		}
+		var p struct {
+			Next    string          `json:"next"`
+			Results json.RawMessage `json:"results"`
+		}
+		if err := json.Unmarshal(jsonData, &p); err != nil {
+			return err
+		}
+		if err := addPage(p.Results); err != nil {
+			return err
+		}
+		if p.Next == "" {
+			return nil
+		}
+	}
+}
+
+// CancelFilter - selects the open orders to cancel, empty fields match any order
+type CancelFilter struct {
+	Market string
+	Side   string
+	IDs    []int64
+}
+
+// CancelResult - outcome of cancelling one order, Err is nil if it was closed
+type CancelResult struct {
+	ID  int64
+	Err error
+}
+
+// maxConcurrentCancels - maximum close order requests done at the same time by CancelOrders
+const maxConcurrentCancels = 4
+
+// CancelOrders - close the open orders matching filter, Tauros has no bulk cancel endpoint so
+// the orders are closed concurrently and a failure does not stop the rest from being closed.
+// The error is only set if the open orders could not be listed.
+func CancelOrders(filter CancelFilter) ([]CancelResult, error) {
+	return cancelOrders(filter, "Token "+apiToken)
+}
+
+// CancelOrdersWithToken - CancelOrders with token instead of the api token of Init, for a second
+// set of credentials like the one of a watchdog
+func CancelOrdersWithToken(token string, filter CancelFilter) ([]CancelResult, error) {
+	return cancelOrders(filter, "Token "+token)
+}
+
+func cancelOrders(filter CancelFilter, authorization string) ([]CancelResult, error) {
+	ids := filter.IDs
+	if filter.Market != "" || filter.Side != "" || len(ids) == 0 {
+		orders, err := getOpenOrders(authorization)
+		if err != nil {
+			return nil, fmt.Errorf("CancelOrders-> %v", err)
+		}
+		ids = nil
+		for _, o := range orders {
+			if filter.matches(o) {
+				ids = append(ids, o.ID)
+			}
+		}
+	}
+	results := make([]CancelResult, len(ids))
+	sem := make(chan struct{}, maxConcurrentCancels)
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, id int64) {
+			defer wg.Done()
+			results[i] = CancelResult{ID: id, Err: closeOrder(id, authorization)}
+			<-sem
+		}(i, id)
+	}
+	wg.Wait()
+	return results, nil
+}
+
+func (f CancelFilter) matches(o Order) bool {
+	if f.Market != "" && !strings.EqualFold(f.Market, o.Market) {
+		return false
+	}
+	if f.Side != "" && !strings.EqualFold(f.Side, o.Side) {
+		return false
+	}
+	if len(f.IDs) == 0 {
+		return true
+	}
+	for _, id := range f.IDs {
+		if id == o.ID {
+			return true
+		}
+	}
+	return false
+}
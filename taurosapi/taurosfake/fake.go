@@ -0,0 +1,849 @@
+// Package taurosfake serves the Tauros api endpoints used by taurosapi from an in memory order book,
+// with fault injection, so the bots can be run against it instead of the real exchange.
+package taurosfake
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"git.vmo.mx/Tauros/tradingbot/exchange"
+	tau "git.vmo.mx/Tauros/tradingbot/taurosapi"
+	ws "github.com/gorilla/websocket"
+	"github.com/shopspring/decimal"
+)
+
+// page size of the paginated endpoints
+const pageSize = 50
+
+type order struct {
+	tau.OrderDetail
+	external bool // order of another user, it is only part of the public book
+}
+
+// Server - fake Tauros api, its URL is meant to be passed to taurosapi.SetURL
+type Server struct {
+	*httptest.Server
+	mux         sync.Mutex
+	token       string
+	email       string
+	password    string
+	twoFactor   bool
+	jwtLifetime time.Duration
+	markets     map[string]tau.Market
+	coins       []tau.Coin
+	balances    map[string]*tau.Balance
+	orders      map[int64]*order
+	nextID      int64
+	trades      []tau.Trade
+	transfers   []tau.Transfer
+	public      map[string][]tau.PublicTrade
+	latency     time.Duration
+	failNext    int
+	failStatus  int
+	badToken    bool
+	fillRatio   decimal.Decimal
+	clients     map[*ws.Conn]bool
+	requests    []string
+}
+
+// DefaultMarkets - trading rules served by a new fake server
+var DefaultMarkets = []tau.Market{
+	{Name: "btc-mxn", LeftCoin: "btc", RightCoin: "mxn", PricePrecision: 2, AmountPrecision: 8,
+		MinAmount: decimal.New(1, -5), MinValue: decimal.New(5, 0), MakerFee: decimal.New(25, -4), TakerFee: decimal.New(5, -3)},
+	{Name: "eth-mxn", LeftCoin: "eth", RightCoin: "mxn", PricePrecision: 2, AmountPrecision: 8,
+		MinAmount: decimal.New(1, -4), MinValue: decimal.New(5, 0), MakerFee: decimal.New(25, -4), TakerFee: decimal.New(5, -3)},
+	{Name: "ltc-mxn", LeftCoin: "ltc", RightCoin: "mxn", PricePrecision: 2, AmountPrecision: 8,
+		MinAmount: decimal.New(1, -3), MinValue: decimal.New(5, 0), MakerFee: decimal.New(25, -4), TakerFee: decimal.New(5, -3)},
+}
+
+// DefaultCoins - coins served by a new fake server
+var DefaultCoins = []tau.Coin{
+	{Coin: "btc", MinWithdrawal: decimal.New(1, -3), FeeWithdrawal: decimal.New(5, -4), ConfirmationsRequired: 3},
+	{Coin: "eth", MinWithdrawal: decimal.New(1, -2), FeeWithdrawal: decimal.New(5, -3), ConfirmationsRequired: 12},
+	{Coin: "ltc", MinWithdrawal: decimal.New(1, -2), FeeWithdrawal: decimal.New(1, -3), ConfirmationsRequired: 6},
+}
+
+var upgrader = ws.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
+
+// New - start a fake server that accepts the api token, with the default markets and coins and empty balances
+func New(token string) *Server {
+	s := &Server{
+		token:       token,
+		jwtLifetime: time.Hour,
+		markets:     make(map[string]tau.Market),
+		coins:       DefaultCoins,
+		balances:    make(map[string]*tau.Balance),
+		orders:      make(map[int64]*order),
+		nextID:      1000,
+		public:      make(map[string][]tau.PublicTrade),
+		clients:     make(map[*ws.Conn]bool),
+	}
+	for _, m := range DefaultMarkets {
+		s.markets[m.Name] = m
+		s.balance(m.LeftCoin)
+		s.balance(m.RightCoin)
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// WebsocketURL - url of the notifications websocket, the jwt token goes in the token query parameter
+func (s *Server) WebsocketURL() string {
+	return "ws" + strings.TrimPrefix(s.URL, "http") + "/ws"
+}
+
+// Close - disconnect the websocket clients and stop the server
+func (s *Server) Close() {
+	s.mux.Lock()
+	for c := range s.clients {
+		c.Close()
+	}
+	s.mux.Unlock()
+	s.Server.Close()
+}
+
+// SetCredentials - email and password accepted by auth/signin, if twoFactor is set a code is also required
+func (s *Server) SetCredentials(email, password string, twoFactor bool) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.email, s.password, s.twoFactor = email, password, twoFactor
+}
+
+// SetJWTLifetime - how long the jwt tokens issued by the server are valid
+func (s *Server) SetJWTLifetime(d time.Duration) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.jwtLifetime = d
+}
+
+// SetLatency - delay every response by d
+func (s *Server) SetLatency(d time.Duration) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.latency = d
+}
+
+// FailNext - answer the next n requests with the http status, like a 500 or 503 from the load balancer
+func (s *Server) FailNext(n int, status int) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.failNext, s.failStatus = n, status
+}
+
+// SetInvalidToken - reject the api token and jwt tokens as if they had been revoked
+func (s *Server) SetInvalidToken(invalid bool) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.badToken = invalid
+}
+
+// SetPartialFill - when a new order crosses the book only fill ratio of the matched amount and leave
+// the rest open, zero fills completely
+func (s *Server) SetPartialFill(ratio decimal.Decimal) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.fillRatio = ratio
+}
+
+// SetBalance - set the available balance of a coin
+func (s *Server) SetBalance(coin string, available decimal.Decimal) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.balance(coin).Balances.Available = available
+}
+
+// Balance - available and frozen balance of a coin
+func (s *Server) Balance(coin string) (available, frozen decimal.Decimal) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	b := s.balance(coin)
+	return b.Balances.Available, b.Balances.Frozen
+}
+
+// AddBookOrder - add an order of another user to the book, it returns the order id
+func (s *Server) AddBookOrder(market, side string, price, amount decimal.Decimal) int64 {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	o := s.newOrder(strings.ToLower(market), side, price, amount)
+	o.external = true
+	return o.ID
+}
+
+// Fill - another user takes amount of one of the user orders, at the order price
+func (s *Server) Fill(orderID int64, amount decimal.Decimal) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	o, ok := s.orders[orderID]
+	if !ok || o.external || !o.IsOpen {
+		return fmt.Errorf("order %d is not an open order of the user", orderID)
+	}
+	if amount.GreaterThan(o.Amount) {
+		amount = o.Amount
+	}
+	s.fill(o, amount, o.Price, true)
+	return nil
+}
+
+// Deposit - credit a deposit of coin, it is pending until it has the confirmations required by the coin
+func (s *Server) Deposit(coin string, amount decimal.Decimal, confirmations int) tau.Transfer {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	coin = strings.ToLower(coin)
+	b := s.balance(coin)
+	status := "pending"
+	if confirmations >= s.coin(coin).ConfirmationsRequired {
+		status = "completed"
+		b.Balances.Available = b.Balances.Available.Add(amount)
+	} else {
+		b.Balances.Pending = b.Balances.Pending.Add(amount)
+	}
+	return s.newTransfer(tau.Deposit, coin, amount, decimal.Zero, b.Address, confirmations, status)
+}
+
+// OpenOrders - open orders of the user
+func (s *Server) OpenOrders() []tau.Order {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.openOrders()
+}
+
+// Order - an order of the user, open or closed
+func (s *Server) Order(orderID int64) (tau.OrderDetail, bool) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	o, ok := s.orders[orderID]
+	if !ok || o.external {
+		return tau.OrderDetail{}, false
+	}
+	return o.OrderDetail, true
+}
+
+// Requests - method and endpoint of every request received, oldest first
+func (s *Server) Requests() []string {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	s.mux.Lock()
+	latency := s.latency
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+	fail := s.failNext > 0
+	if fail {
+		s.failNext--
+	}
+	status := s.failStatus
+	s.mux.Unlock()
+	time.Sleep(latency)
+	if fail {
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+	if r.URL.Path == "/ws" {
+		s.serveWebsocket(w, r)
+		return
+	}
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/"), "/")
+	i := strings.Index(path, "/")
+	if i < 0 {
+		writeError(w, http.StatusNotFound, "Not found.")
+		return
+	}
+	version, service := path[:i], path[i+1:]
+	var m tau.Message
+	if r.Method == "POST" {
+		if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid json: "+err.Error())
+			return
+		}
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	var data interface{}
+	var err error
+	switch version + "/" + service {
+	case "v1/data/coins":
+		data = map[string]interface{}{"crypto": s.coins}
+	case "v1/data/listmarkets":
+		data = map[string]interface{}{"markets": s.marketList()}
+	case "v1/trading/orderbook":
+		data, err = s.orderBook(r.URL.Query().Get("market"))
+	case "v1/data/ticker":
+		data, err = s.ticker(r.URL.Query().Get("market"))
+	case "v1/trading/trades":
+		data = s.public[strings.ToLower(r.URL.Query().Get("market"))]
+	case "v2/auth/signin":
+		data, err = s.signIn(m)
+	case "v2/auth/verify-tfa":
+		data, err = s.verifyTwoFactor(r, m)
+	case "v2/auth/refresh-token":
+		data, err = s.refreshToken(r)
+	default:
+		if !s.validToken(r) {
+			writeError(w, http.StatusUnauthorized, "Invalid token.")
+			return
+		}
+		data, err = s.private(r, service, m)
+	}
+	if err != nil {
+		status := http.StatusBadRequest
+		if err == errNotFound {
+			status = http.StatusNotFound
+		}
+		if err == errUnauthorized {
+			status = http.StatusUnauthorized
+		}
+		writeError(w, status, err.Error())
+		return
+	}
+	writeData(w, version, data)
+}
+
+func (s *Server) private(r *http.Request, service string, m tau.Message) (interface{}, error) {
+	query := r.URL.Query()
+	switch service {
+	case "trading/placeorder":
+		return s.placeOrder(m)
+	case "trading/closeorder":
+		return nil, s.closeOrder(m.ID)
+	case "trading/myopenorders":
+		return s.openOrders(), nil
+	case "trading/getorder":
+		id, _ := strconv.ParseInt(query.Get("id"), 10, 64)
+		o, ok := s.orders[id]
+		if !ok || o.external {
+			return nil, errNotFound
+		}
+		return o.OrderDetail, nil
+	case "trading/myclosedorders":
+		var closed []tau.OrderDetail
+		for _, o := range s.sortedOrders() {
+			if !o.external && !o.IsOpen && matchesHistory(query, o.Market, o.CreatedAt) {
+				closed = append(closed, o.OrderDetail)
+			}
+		}
+		return paginate(r, len(closed), func(from, to int) interface{} { return closed[from:to] }), nil
+	case "trading/mytrades":
+		var trades []tau.Trade
+		for _, t := range s.trades {
+			if matchesHistory(query, t.Market, t.CreatedAt) {
+				trades = append(trades, t)
+			}
+		}
+		return paginate(r, len(trades), func(from, to int) interface{} { return trades[from:to] }), nil
+	case "data/listbalances":
+		var wallets []tau.Balance
+		for _, b := range s.balances {
+			wallets = append(wallets, *b)
+		}
+		sort.Slice(wallets, func(i, j int) bool { return wallets[i].Coin < wallets[j].Coin })
+		return map[string]interface{}{"wallets": wallets}, nil
+	case "data/withdraw":
+		return s.withdraw(m)
+	case "data/transfershistory":
+		var transfers []tau.Transfer
+		for _, t := range s.transfers {
+			if t.Type == query.Get("type") && (query.Get("coin") == "" || strings.EqualFold(query.Get("coin"), t.Coin)) &&
+				matchesHistory(query, "", t.CreatedAt) {
+				transfers = append(transfers, t)
+			}
+		}
+		return paginate(r, len(transfers), func(from, to int) interface{} { return transfers[from:to] }), nil
+	case "data/getdepositaddress":
+		coin := strings.ToLower(query.Get("coin"))
+		return map[string]string{"coin": coin, "address": s.balance(coin).Address}, nil
+	}
+	return nil, errNotFound
+}
+
+func (s *Server) withdraw(m tau.Message) (interface{}, error) {
+	coin, amount := strings.ToLower(m.Coin), valueOf(m.Amount)
+	c := s.coin(coin)
+	if c.Coin == "" {
+		return nil, fmt.Errorf("Invalid coin %s.", m.Coin)
+	}
+	if m.Address == "" || amount.LessThan(c.MinWithdrawal) {
+		return nil, fmt.Errorf("Invalid withdrawal, minimum is %s.", c.MinWithdrawal)
+	}
+	b := s.balance(coin)
+	if b.Balances.Available.LessThan(amount) {
+		return nil, fmt.Errorf("Insufficient %s balance.", coin)
+	}
+	b.Balances.Available = b.Balances.Available.Sub(amount)
+	return s.newTransfer(tau.Withdrawal, coin, amount, c.FeeWithdrawal, m.Address, 0, "pending"), nil
+}
+
+func (s *Server) newTransfer(transferType, coin string, amount, fee decimal.Decimal, address string, confirmations int, status string) tau.Transfer {
+	s.nextID++
+	t := tau.Transfer{
+		ID: s.nextID, Coin: coin, Type: transferType, Amount: amount, Fee: fee, Address: address,
+		TxHash: fmt.Sprintf("fake-tx-%d", s.nextID), Confirmations: confirmations, Status: status,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+	s.transfers = append(s.transfers, t)
+	s.notify(tau.TauWsTransferMessage{
+		Title:       "New " + transferType,
+		Description: fmt.Sprintf("%s %s %s", transferType, amount, coin),
+		Type:        tau.WsTransfer,
+		Date:        t.CreatedAt,
+		Object:      t,
+	})
+	return t
+}
+
+func (s *Server) coin(coin string) tau.Coin {
+	for _, c := range s.coins {
+		if c.Coin == coin {
+			return c
+		}
+	}
+	return tau.Coin{}
+}
+
+func (s *Server) placeOrder(m tau.Message) (interface{}, error) {
+	market, ok := s.markets[strings.ToLower(m.Market)]
+	if !ok {
+		return nil, fmt.Errorf("Invalid market %s.", m.Market)
+	}
+	amount, price := valueOf(m.Amount), valueOf(m.Price)
+	if m.Side != "buy" && m.Side != "sell" {
+		return nil, fmt.Errorf("Invalid side %s.", m.Side)
+	}
+	if amount.Sign() <= 0 || price.Sign() <= 0 {
+		return nil, errors.New("Amount and price must be greater than zero.")
+	}
+	rules := exchange.TauRules(market)
+	if !rules.RoundPrice(m.Side, price).Equal(price) || !rules.RoundAmount(amount).Equal(amount) {
+		return nil, errors.New("Invalid price or amount precision.")
+	}
+	if err := rules.CheckOrder(amount, price); err != nil {
+		return nil, fmt.Errorf("Invalid order: %v.", err)
+	}
+	coin, cost := market.LeftCoin, amount
+	if m.Side == "buy" {
+		coin, cost = market.RightCoin, amount.Mul(price)
+	}
+	b := s.balance(coin)
+	if b.Balances.Available.LessThan(cost) {
+		return nil, fmt.Errorf("Insufficient %s balance.", coin)
+	}
+	b.Balances.Available = b.Balances.Available.Sub(cost)
+	b.Balances.Frozen = b.Balances.Frozen.Add(cost)
+	o := s.newOrder(market.Name, m.Side, price, amount)
+	s.match(o, rules)
+	return map[string]int64{"id": o.ID}, nil
+}
+
+// match fills a new order of the user against the crossing orders of other users, at their price
+func (s *Server) match(o *order, rules exchange.MarketRules) {
+	for _, other := range s.sortedOrders() {
+		if !o.IsOpen || !other.external || !other.IsOpen || other.Market != o.Market || other.Side == o.Side {
+			continue
+		}
+		if (o.Side == "buy" && other.Price.GreaterThan(o.Price)) || (o.Side == "sell" && other.Price.LessThan(o.Price)) {
+			continue
+		}
+		amount := decimal.Min(o.Amount, other.Amount)
+		partial := s.fillRatio.Sign() > 0
+		if partial {
+			amount = rules.RoundAmount(amount.Mul(s.fillRatio))
+		}
+		if amount.Sign() > 0 {
+			s.fill(o, amount, other.Price, false)
+			fillOrder(&other.OrderDetail, amount)
+		}
+		if partial {
+			return
+		}
+	}
+}
+
+// fill moves the balances of a fill of amount of an order of the user at price
+func (s *Server) fill(o *order, amount, price decimal.Decimal, maker bool) {
+	market := s.markets[o.Market]
+	fee := market.TakerFee
+	if maker {
+		fee = market.MakerFee
+	}
+	left, right := s.balance(market.LeftCoin), s.balance(market.RightCoin)
+	value := amount.Mul(price)
+	var paid, received decimal.Decimal
+	if o.Side == "buy" {
+		frozen := amount.Mul(o.Price)
+		right.Balances.Frozen = right.Balances.Frozen.Sub(frozen)
+		right.Balances.Available = right.Balances.Available.Add(frozen.Sub(value))
+		paid, received = value, amount.Sub(amount.Mul(fee))
+		left.Balances.Available = left.Balances.Available.Add(received)
+	} else {
+		left.Balances.Frozen = left.Balances.Frozen.Sub(amount)
+		paid, received = amount, value.Sub(value.Mul(fee))
+		right.Balances.Available = right.Balances.Available.Add(received)
+	}
+	feeAmount := amount.Mul(fee)
+	feeCoin := market.LeftCoin
+	if o.Side == "sell" {
+		feeAmount, feeCoin = value.Mul(fee), market.RightCoin
+	}
+	fillOrder(&o.OrderDetail, amount)
+	o.FeeAmountPaid = o.FeeAmountPaid.Add(feeAmount)
+	now := time.Now().UTC()
+	s.nextID++
+	s.trades = append(s.trades, tau.Trade{
+		ID: s.nextID, OrderID: o.ID, Market: o.Market, Side: o.Side, Amount: amount, Price: price,
+		Value: value, Fee: feeAmount, FeeCoin: feeCoin, IsMaker: maker, CreatedAt: now.Format(time.RFC3339),
+	})
+	taker := o.Side
+	if maker {
+		taker = opposite(o.Side)
+	}
+	s.public[o.Market] = append([]tau.PublicTrade{{
+		Price: price, Amount: amount, Value: value, Side: taker, CreatedAt: now.Format(time.RFC3339),
+	}}, s.public[o.Market]...)
+	s.notify(tau.TauWsMessage{
+		Title:       "New trade",
+		Description: fmt.Sprintf("%s %s %s at %s", strings.ToUpper(o.Side), amount, market.LeftCoin, price),
+		Type:        tau.WsTrade,
+		Date:        now.Format(time.RFC3339),
+		Object: tau.TauWsObject{
+			ID: o.ID, Market: o.Market, Side: strings.ToUpper(o.Side), LeftCoin: market.LeftCoin, RightCoin: market.RightCoin,
+			Amount: amount, Price: price, Value: value, AmountPaid: paid, AmountReceived: received,
+			FeeAmountPaid: feeAmount, FeeDecimal: fee, FeePercent: fee.Mul(decimal.New(100, 0)),
+			Filled: o.Filled, InitialAmount: o.InitialAmount, InitialValue: o.InitialValue,
+			IsOpen: o.IsOpen, CreatedAt: o.CreatedAt, ClosedAt: o.ClosedAt,
+		},
+	})
+}
+
+// fillOrder updates the amounts of an order after a fill, closing it if nothing is left
+func fillOrder(o *tau.OrderDetail, amount decimal.Decimal) {
+	o.Amount = o.Amount.Sub(amount)
+	o.Filled = o.Filled.Add(amount)
+	o.Value = o.Amount.Mul(o.Price)
+	if o.Amount.Sign() <= 0 {
+		o.IsOpen = false
+		o.Status = tau.OrderFilled
+		o.ClosedAt = time.Now().UTC().Format(time.RFC3339)
+	}
+}
+
+func (s *Server) closeOrder(orderID int64) error {
+	o, ok := s.orders[orderID]
+	if !ok || o.external {
+		return errNotFound
+	}
+	if !o.IsOpen {
+		return fmt.Errorf("Order %d is already closed.", orderID)
+	}
+	market := s.markets[o.Market]
+	coin, frozen := market.LeftCoin, o.Amount
+	if o.Side == "buy" {
+		coin, frozen = market.RightCoin, o.Amount.Mul(o.Price)
+	}
+	b := s.balance(coin)
+	b.Balances.Frozen = b.Balances.Frozen.Sub(frozen)
+	b.Balances.Available = b.Balances.Available.Add(frozen)
+	o.IsOpen = false
+	o.Status = tau.OrderCancelled
+	o.ClosedAt = time.Now().UTC().Format(time.RFC3339)
+	return nil
+}
+
+func (s *Server) newOrder(market, side string, price, amount decimal.Decimal) *order {
+	s.nextID++
+	o := &order{OrderDetail: tau.OrderDetail{
+		Order: tau.Order{
+			ID: s.nextID, Market: market, Side: side, Amount: amount, InitialAmount: amount,
+			Value: amount.Mul(price), InitialValue: amount.Mul(price), Price: price,
+			CreatedAt: time.Now().UTC().Format(time.RFC3339),
+		},
+		IsOpen: true,
+		Status: tau.OrderOpen,
+	}}
+	s.orders[o.ID] = o
+	return o
+}
+
+func (s *Server) openOrders() []tau.Order {
+	orders := []tau.Order{}
+	for _, o := range s.sortedOrders() {
+		if !o.external && o.IsOpen {
+			orders = append(orders, o.Order)
+		}
+	}
+	return orders
+}
+
+// sortedOrders returns all orders, buys before sells, sorted by price best first and then by age
+func (s *Server) sortedOrders() []*order {
+	orders := make([]*order, 0, len(s.orders))
+	for _, o := range s.orders {
+		orders = append(orders, o)
+	}
+	sort.Slice(orders, func(i, j int) bool {
+		a, b := orders[i], orders[j]
+		if a.Side != b.Side {
+			return a.Side == "buy"
+		}
+		if !a.Price.Equal(b.Price) {
+			return (a.Side == "buy") == a.Price.GreaterThan(b.Price)
+		}
+		return a.ID < b.ID
+	})
+	return orders
+}
+
+func (s *Server) orderBook(market string) (tau.OrderBook, error) {
+	market = strings.ToLower(market)
+	if _, ok := s.markets[market]; !ok {
+		return tau.OrderBook{}, fmt.Errorf("Invalid market %s.", market)
+	}
+	book := tau.OrderBook{Bids: []tau.BookEntry{}, Asks: []tau.BookEntry{}}
+	for _, o := range s.sortedOrders() {
+		if !o.IsOpen || o.Market != market {
+			continue
+		}
+		entries := &book.Asks
+		if o.Side == "buy" {
+			entries = &book.Bids
+		}
+		if n := len(*entries); n > 0 && (*entries)[n-1].Price.Equal(o.Price) {
+			(*entries)[n-1].Amount = (*entries)[n-1].Amount.Add(o.Amount)
+			(*entries)[n-1].Value = (*entries)[n-1].Amount.Mul(o.Price)
+			continue
+		}
+		*entries = append(*entries, tau.BookEntry{Price: o.Price, Amount: o.Amount, Value: o.Amount.Mul(o.Price)})
+	}
+	sort.SliceStable(book.Bids, func(i, j int) bool { return book.Bids[i].Price.GreaterThan(book.Bids[j].Price) })
+	sort.SliceStable(book.Asks, func(i, j int) bool { return book.Asks[i].Price.LessThan(book.Asks[j].Price) })
+	return book, nil
+}
+
+func (s *Server) ticker(market string) (tau.Ticker, error) {
+	book, err := s.orderBook(market)
+	if err != nil {
+		return tau.Ticker{}, err
+	}
+	t := tau.Ticker{Market: strings.ToLower(market), CreatedAt: time.Now().UTC().Format(time.RFC3339)}
+	if bid, ok := book.BestBid(); ok {
+		t.Bid = bid.Price
+	}
+	if ask, ok := book.BestAsk(); ok {
+		t.Ask = ask.Price
+	}
+	for i, p := range s.public[t.Market] {
+		if i == 0 {
+			t.Last, t.High, t.Low = p.Price, p.Price, p.Price
+		}
+		t.High = decimal.Max(t.High, p.Price)
+		t.Low = decimal.Min(t.Low, p.Price)
+		t.Volume = t.Volume.Add(p.Amount)
+	}
+	return t, nil
+}
+
+func (s *Server) marketList() []tau.Market {
+	var markets []tau.Market
+	for _, m := range s.markets {
+		markets = append(markets, m)
+	}
+	sort.Slice(markets, func(i, j int) bool { return markets[i].Name < markets[j].Name })
+	return markets
+}
+
+func (s *Server) balance(coin string) *tau.Balance {
+	b, ok := s.balances[coin]
+	if !ok {
+		b = &tau.Balance{Coin: coin, CoinName: strings.ToUpper(coin), Address: "fake-" + coin + "-address"}
+		s.balances[coin] = b
+	}
+	return b
+}
+
+func opposite(side string) string {
+	if side == "buy" {
+		return "sell"
+	}
+	return "buy"
+}
+
+func matchesHistory(query map[string][]string, market, createdAt string) bool {
+	if m := first(query["market"]); m != "" && !strings.EqualFold(m, market) {
+		return false
+	}
+	if since := first(query["since"]); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		created, err2 := time.Parse(time.RFC3339, createdAt)
+		if err == nil && err2 == nil && created.Before(t) {
+			return false
+		}
+	}
+	return true
+}
+
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// paginate returns the requested page of a list in the format of the paginated Tauros endpoints
+func paginate(r *http.Request, count int, slice func(from, to int) interface{}) interface{} {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	from, to := (page-1)*pageSize, page*pageSize
+	if from > count {
+		from = count
+	}
+	if to > count {
+		to = count
+	}
+	next := ""
+	if to < count {
+		q := r.URL.Query()
+		q.Set("page", strconv.Itoa(page+1))
+		next = r.URL.Path + "?" + q.Encode()
+	}
+	results := slice(from, to)
+	if from == to {
+		results = []interface{}{}
+	}
+	return map[string]interface{}{"count": count, "next": next, "results": results}
+}
+
+var errNotFound = errors.New("Not found.")
+var errUnauthorized = errors.New("Invalid token.")
+
+func (s *Server) validToken(r *http.Request) bool {
+	return !s.badToken && r.Header.Get("Authorization") == "Token "+s.token
+}
+
+type claims struct {
+	Email      string `json:"email"`
+	Exp        int64  `json:"exp"`
+	TwoFactor  bool   `json:"tfa_pending,omitempty"`
+	IssuedNano int64  `json:"iat_nano"`
+}
+
+func (s *Server) newJWT(pending bool) string {
+	c, _ := json.Marshal(claims{
+		Email:      s.email,
+		Exp:        time.Now().Add(s.jwtLifetime).Unix(),
+		TwoFactor:  pending,
+		IssuedNano: time.Now().UnixNano(),
+	})
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`)) + "." + enc.EncodeToString(c) + ".fake"
+}
+
+// checkJWT returns the claims of a jwt issued by the server if it is still valid
+func (s *Server) checkJWT(token string) (claims, bool) {
+	var c claims
+	parts := strings.Split(token, ".")
+	if s.badToken || len(parts) != 3 || parts[2] != "fake" {
+		return c, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || json.Unmarshal(payload, &c) != nil {
+		return c, false
+	}
+	return c, time.Now().Unix() < c.Exp
+}
+
+func (s *Server) signIn(m tau.Message) (interface{}, error) {
+	if s.email == "" || m.Email != s.email || m.Password != s.password {
+		return nil, errors.New("Invalid credentials.")
+	}
+	return map[string]interface{}{"token": s.newJWT(s.twoFactor), "two_factor": s.twoFactor}, nil
+}
+
+func (s *Server) verifyTwoFactor(r *http.Request, m tau.Message) (interface{}, error) {
+	c, ok := s.checkJWT(strings.TrimPrefix(r.Header.Get("Authorization"), "JWT "))
+	if !ok || !c.TwoFactor {
+		return nil, errUnauthorized
+	}
+	if _, err := strconv.Atoi(m.Code); err != nil || len(m.Code) != 6 {
+		return nil, errors.New("Invalid code.")
+	}
+	return map[string]interface{}{"token": s.newJWT(false)}, nil
+}
+
+func (s *Server) refreshToken(r *http.Request) (interface{}, error) {
+	c, ok := s.checkJWT(strings.TrimPrefix(r.Header.Get("Authorization"), "JWT "))
+	if !ok || c.TwoFactor {
+		return nil, errUnauthorized
+	}
+	return map[string]interface{}{"token": s.newJWT(false)}, nil
+}
+
+func (s *Server) serveWebsocket(w http.ResponseWriter, r *http.Request) {
+	s.mux.Lock()
+	c, ok := s.checkJWT(r.URL.Query().Get("token"))
+	s.mux.Unlock()
+	if !ok || c.TwoFactor {
+		http.Error(w, "Invalid token.", http.StatusUnauthorized)
+		return
+	}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	s.mux.Lock()
+	s.clients[conn] = true
+	s.mux.Unlock()
+	// the server only pushes notifications, reading detects when the client goes away
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			break
+		}
+	}
+	s.mux.Lock()
+	delete(s.clients, conn)
+	s.mux.Unlock()
+	conn.Close()
+}
+
+// notify sends a notification to all websocket clients, must be called with the mutex locked
+func (s *Server) notify(m interface{}) {
+	for c := range s.clients {
+		if err := c.WriteJSON(m); err != nil {
+			c.Close()
+			delete(s.clients, c)
+		}
+	}
+}
+
+func writeData(w http.ResponseWriter, version string, data interface{}) {
+	field := "data"
+	if version != "v1" {
+		field = "payload"
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"success": true, field: data})
+}
+
+// valueOf - the decimal of a request field, zero if it was not sent
+func valueOf(d *decimal.Decimal) decimal.Decimal {
+	if d == nil {
+		return decimal.Zero
+	}
+	return *d
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "msg": msg})
+}
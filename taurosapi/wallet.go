@@ -0,0 +1,175 @@
+package taurosapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// transfer types
+const (
+	Deposit    = "deposit"
+	Withdrawal = "withdrawal"
+)
+
+// websocket notification types
+const (
+	WsTrade    = "TD"
+	WsTransfer = "TR"
+)
+
+// Transfer - a deposit or withdrawal of the user
+type Transfer struct {
+	ID            int64           `json:"id"`
+	Coin          string          `json:"coin"`
+	Type          string          `json:"type"`
+	Amount        decimal.Decimal `json:"amount"`
+	Fee           decimal.Decimal `json:"fee"`
+	Address       string          `json:"address"`
+	TxHash        string          `json:"tx_hash"`
+	Confirmations int             `json:"confirmations"`
+	Status        string          `json:"status"`
+	CreatedAt     string          `json:"created_at"`
+}
+
+// TauWsTransferMessage - Tauros Websocket transfer notification, sent with type TR
+type TauWsTransferMessage struct {
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Type        string   `json:"type"`
+	Date        string   `json:"date"`
+	Object      Transfer `json:"object"`
+}
+
+// ErrNotWhitelisted - the withdrawal address is not in the withdrawal whitelist of the coin
+var ErrNotWhitelisted = errors.New("withdrawal address is not whitelisted")
+
+var whitelist struct {
+	sync.RWMutex
+	addresses map[string][]string
+}
+
+// SetWithdrawalWhitelist - set the only addresses each coin can be withdrawn to, a coin without
+// addresses cannot be withdrawn
+func SetWithdrawalWhitelist(addresses map[string][]string) {
+	whitelist.Lock()
+	defer whitelist.Unlock()
+	whitelist.addresses = make(map[string][]string, len(addresses))
+	for coin, a := range addresses {
+		whitelist.addresses[strings.ToLower(coin)] = append([]string(nil), a...)
+	}
+}
+
+func whitelisted(coin, address string) bool {
+	whitelist.RLock()
+	defer whitelist.RUnlock()
+	for _, a := range whitelist.addresses[strings.ToLower(coin)] {
+		if a == address {
+			return true
+		}
+	}
+	return false
+}
+
+// Withdraw - request a withdrawal of amount of coin to a whitelisted address, the amount must
+// be at least the coin minimum withdrawal
+func Withdraw(coin string, address string, amount decimal.Decimal) (transfer Transfer, error error) {
+	if !whitelisted(coin, address) {
+		return transfer, fmt.Errorf("Withdraw-> %v: %s %s", ErrNotWhitelisted, coin, address)
+	}
+	coins, err := GetCoins()
+	if err != nil {
+		return transfer, fmt.Errorf("Withdraw-> %v", err)
+	}
+	found := false
+	for _, c := range coins {
+		if strings.EqualFold(c.Coin, coin) {
+			found = true
+			if amount.LessThan(c.MinWithdrawal) {
+				return transfer, fmt.Errorf("Withdraw-> amount %s is less than the %s minimum withdrawal %s", amount, coin, c.MinWithdrawal)
+			}
+		}
+	}
+	if !found {
+		return transfer, fmt.Errorf("Withdraw-> unknown coin %s", coin)
+	}
+	jsonData, err := doTauRequest(1, "POST", "data/withdraw/", &Message{Coin: coin, Address: address, Amount: &amount})
+	if err != nil {
+		return transfer, fmt.Errorf("Withdraw-> %v", err)
+	}
+	if err := json.Unmarshal(jsonData, &transfer); err != nil {
+		return transfer, fmt.Errorf("Withdraw-> %v", err)
+	}
+	return transfer, nil
+}
+
+// GetDeposits - get the deposits of coin made after since, an empty coin or a zero since are not used as filters
+func GetDeposits(coin string, since time.Time) ([]Transfer, error) {
+	transfers, err := getTransfers(Deposit, coin, since)
+	if err != nil {
+		return nil, fmt.Errorf("GetDeposits-> %v", err)
+	}
+	return transfers, nil
+}
+
+// GetWithdrawals - get the withdrawals of coin made after since, an empty coin or a zero since are not used as filters
+func GetWithdrawals(coin string, since time.Time) ([]Transfer, error) {
+	transfers, err := getTransfers(Withdrawal, coin, since)
+	if err != nil {
+		return nil, fmt.Errorf("GetWithdrawals-> %v", err)
+	}
+	return transfers, nil
+}
+
+func getTransfers(transferType string, coin string, since time.Time) ([]Transfer, error) {
+	var transfers []Transfer
+	params := url.Values{}
+	params.Set("type", transferType)
+	if coin != "" {
+		params.Set("coin", coin)
+	}
+	if !since.IsZero() {
+		params.Set("since", since.UTC().Format(time.RFC3339))
+	}
+	err := getPages("data/transfershistory/", params, func(page json.RawMessage) error {
+		var t []Transfer
+		if err := json.Unmarshal(page, &t); err != nil {
+			return err
+		}
+		transfers = append(transfers, t...)
+		return nil
+	})
+	return transfers, err
+}
+
+// ParseWsMessage - decode a websocket notification, trades (type TD) are returned as a TauWsMessage
+// and transfers (type TR) as a TauWsTransferMessage
+func ParseWsMessage(data []byte) (interface{}, error) {
+	var header struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, fmt.Errorf("ParseWsMessage-> %v", err)
+	}
+	switch header.Type {
+	case WsTrade:
+		var m TauWsMessage
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, fmt.Errorf("ParseWsMessage-> %v", err)
+		}
+		return m, nil
+	case WsTransfer:
+		var m TauWsTransferMessage
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, fmt.Errorf("ParseWsMessage-> %v", err)
+		}
+		return m, nil
+	}
+	return nil, fmt.Errorf("ParseWsMessage-> unknown notification type %s", header.Type)
+}
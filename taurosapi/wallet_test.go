@@ -0,0 +1,71 @@
+package taurosapi_test
+
+import (
+	"strings"
+	"testing"
+
+	tau "git.vmo.mx/Tauros/tradingbot/taurosapi"
+	"git.vmo.mx/Tauros/tradingbot/taurosapi/taurosfake"
+	"github.com/shopspring/decimal"
+)
+
+const testAddress = "bc1qwhitelisted"
+
+func startWallet() *taurosfake.Server {
+	fake := taurosfake.New("test-token")
+	tau.Init(false, "test-token")
+	tau.SetURL(fake.URL)
+	fake.SetBalance("btc", decimal.New(1, 0))
+	tau.SetWithdrawalWhitelist(map[string][]string{"BTC": {testAddress}})
+	return fake
+}
+
+func withdrawRequests(fake *taurosfake.Server) int {
+	n := 0
+	for _, r := range fake.Requests() {
+		if strings.Contains(r, "data/withdraw") {
+			n++
+		}
+	}
+	return n
+}
+
+func TestWithdrawWhitelisted(t *testing.T) {
+	fake := startWallet()
+	defer fake.Close()
+	transfer, err := tau.Withdraw("btc", testAddress, decimal.New(1, -2))
+	if err != nil {
+		t.Fatalf("Withdraw to a whitelisted address: %v", err)
+	}
+	if transfer.Address != testAddress || !transfer.Amount.Equal(decimal.New(1, -2)) || transfer.Type != tau.Withdrawal {
+		t.Errorf("transfer = %+v", transfer)
+	}
+	if available, _ := fake.Balance("btc"); !available.Equal(decimal.New(99, -2)) {
+		t.Errorf("btc balance after withdrawing = %s, want 0.99", available)
+	}
+}
+
+func TestWithdrawRejected(t *testing.T) {
+	fake := startWallet()
+	defer fake.Close()
+	tests := []struct {
+		name, coin, address string
+	}{
+		{"other address", "btc", "bc1qattacker"},
+		{"coin without whitelist", "eth", testAddress},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tau.Withdraw(tt.coin, tt.address, decimal.New(1, -1))
+			if err == nil || !strings.Contains(err.Error(), tau.ErrNotWhitelisted.Error()) {
+				t.Errorf("Withdraw error = %v, want %v", err, tau.ErrNotWhitelisted)
+			}
+		})
+	}
+	if n := withdrawRequests(fake); n != 0 {
+		t.Errorf("%d rejected withdrawals were sent to the exchange", n)
+	}
+	if available, _ := fake.Balance("btc"); !available.Equal(decimal.New(1, 0)) {
+		t.Errorf("btc balance = %s, want it untouched", available)
+	}
+}
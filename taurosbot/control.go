@@ -0,0 +1,146 @@
+package taurosbot //trading-bot
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net"
+	"net/http"
+
+	"github.com/shopspring/decimal"
+	log "github.com/sirupsen/logrus"
+)
+
+// botStatus - state of the bots served by the control endpoint
+type botStatus struct {
+	Market      string          `json:"market"`
+	Exchange    string          `json:"exchange"`
+	Inventory   decimal.Decimal `json:"inventory"`
+	Target      decimal.Decimal `json:"target"`
+	Skew        decimal.Decimal `json:"skew"`
+	Spread      decimal.Decimal `json:"spread"`
+	Rate        decimal.Decimal `json:"exchange_rate"`
+	BuyBalance  decimal.Decimal `json:"buy_balance"`
+	SellBalance decimal.Decimal `json:"sell_balance"`
+	DailyPnL    decimal.Decimal `json:"daily_pnl"`
+	Halted      bool            `json:"halted"`
+	HaltReason  string          `json:"halt_reason,omitempty"`
+	Orders      []statusOrder   `json:"orders"`
+	Closed      []closedOrder   `json:"closed_orders"`
+}
+
+type statusOrder struct {
+	ID     string          `json:"id"`
+	Side   string          `json:"side"`
+	Price  decimal.Decimal `json:"price"`
+	Amount decimal.Decimal `json:"amount"`
+}
+
+// startControl serves the control endpoint at addr until the process exits. Requests must have
+// token as "Authorization: Bearer <token>", without a token it only listens on localhost unless
+// addr has a host
+func startControl(addr, token string) {
+	addr = controlAddr(addr, token)
+	if token == "" {
+		log.Warnf("No control_token in the credentials, the control endpoint at %s is not authenticated", addr)
+	}
+	log.Infof("Control endpoint listening at %s", addr)
+	go func() {
+		if err := http.ListenAndServe(addr, controlHandler(token)); err != nil {
+			log.Errorf("Control endpoint stopped: %v", err)
+		}
+	}()
+}
+
+// controlAddr - addr with the localhost host if it has none and there is no token
+func controlAddr(addr, token string) string {
+	if token != "" {
+		return addr
+	}
+	if host, port, err := net.SplitHostPort(addr); err == nil && host == "" {
+		return net.JoinHostPort("127.0.0.1", port)
+	}
+	return addr
+}
+
+// controlHandler - the control endpoint, requiring token if it is not empty
+func controlHandler(token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", handleStatus)
+	mux.HandleFunc("/halt", handleHalt)
+	mux.HandleFunc("/resume", handleResume)
+	if token == "" {
+		return mux
+	}
+	want := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			log.Warnf("Unauthorized control request %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+func getStatus() botStatus {
+	marketData.RLock()
+	s := botStatus{
+		Market:      tauMarket,
+		Exchange:    venue.Name(),
+		Inventory:   marketData.inventory,
+		Target:      inventoryConfig().Target,
+		Skew:        marketData.skew,
+		Spread:      marketData.spread,
+		Rate:        marketData.currentExchangeRate,
+		BuyBalance:  marketData.buyBalance,
+		SellBalance: marketData.sellBalance,
+	}
+	marketData.RUnlock()
+	s.DailyPnL = dailyPnL(markPrice())
+	s.Halted, s.HaltReason = riskHalted()
+	myOrders.RLock()
+	for id, o := range myOrders.orders {
+		s.Orders = append(s.Orders, statusOrder{ID: string(id), Side: o.Side, Price: o.Price, Amount: o.Amount})
+	}
+	myOrders.RUnlock()
+	closedOrders.RLock()
+	s.Closed = append([]closedOrder(nil), closedOrders.orders...)
+	closedOrders.RUnlock()
+	return s
+}
+
+func handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeStatus(w)
+}
+
+// handleHalt - kill switch, cancels all the bots orders and halts quoting
+func handleHalt(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	haltBots("halted from the control endpoint by " + r.RemoteAddr)
+	writeStatus(w)
+}
+
+// handleResume - lets the bots quote again after a halt
+func handleResume(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	log.Warnf("Resume requested from the control endpoint by %s", r.RemoteAddr)
+	resumeBots()
+	writeStatus(w)
+}
+
+func writeStatus(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(getStatus()); err != nil {
+		log.Warnf("Unable to write control status: %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+package taurosbot //trading-bot
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestControlAddr(t *testing.T) {
+	tests := []struct {
+		addr, token, want string
+	}{
+		{":2230", "", "127.0.0.1:2230"},
+		{":2230", "secret", ":2230"},
+		{"0.0.0.0:2230", "", "0.0.0.0:2230"},
+		{"10.0.0.5:2230", "secret", "10.0.0.5:2230"},
+	}
+	for _, tt := range tests {
+		if got := controlAddr(tt.addr, tt.token); got != tt.want {
+			t.Errorf("controlAddr(%q, %q) = %q, want %q", tt.addr, tt.token, got, tt.want)
+		}
+	}
+}
+
+func TestControlToken(t *testing.T) {
+	startPaper()
+	srv := httptest.NewServer(controlHandler("secret"))
+	defer srv.Close()
+	request := func(method, path, authorization string) int {
+		req, err := http.NewRequest(method, srv.URL+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	for _, auth := range []string{"", "Bearer wrong", "secret", "Bearer secretx"} {
+		if code := request("POST", "/halt", auth); code != http.StatusUnauthorized {
+			t.Errorf("POST /halt with %q = %d, want 401", auth, code)
+		}
+		if code := request("GET", "/status", auth); code != http.StatusUnauthorized {
+			t.Errorf("GET /status with %q = %d, want 401", auth, code)
+		}
+	}
+	if halted, _ := riskHalted(); halted {
+		t.Fatal("unauthorized /halt halted the bots")
+	}
+
+	if code := request("GET", "/status", "Bearer secret"); code != http.StatusOK {
+		t.Errorf("GET /status = %d, want 200", code)
+	}
+	if code := request("POST", "/halt", "Bearer secret"); code != http.StatusOK {
+		t.Errorf("POST /halt = %d, want 200", code)
+	}
+	if halted, _ := riskHalted(); !halted {
+		t.Error("POST /halt did not halt the bots")
+	}
+	if code := request("POST", "/resume", "Bearer secret"); code != http.StatusOK {
+		t.Errorf("POST /resume = %d, want 200", code)
+	}
+	if halted, _ := riskHalted(); halted {
+		t.Error("POST /resume did not resume the bots")
+	}
+}
@@ -0,0 +1,58 @@
+package taurosbot //trading-bot
+
+import (
+	"errors"
+
+	"github.com/shopspring/decimal"
+)
+
+// inventoryModel - the quotes are skewed towards a target inventory, the share of the market
+// balances held in the base coin. Holding more base than the target moves both prices down so
+// selling is more likely than buying, and the other way around
+type inventoryModel struct {
+	Target        decimal.Decimal //target base coin share of the balances, between 0 and 1
+	Skew          decimal.Decimal //price shift per unit of deviation from the target
+	MaxSkew       decimal.Decimal //maximum price shift, as a fraction of the price, no maximum if zero
+	SizeReduction decimal.Decimal //order size reduction per unit of deviation, on the side that moves away from the target
+}
+
+// defaultInventory is used when the bots configuration has no Inventory: no skew around half and half
+var defaultInventory = inventoryModel{Target: decimal.New(5, -1)}
+
+func checkInventoryModel(m *inventoryModel) error {
+	if m.Target.Sign() < 0 || m.Target.GreaterThan(decimal.New(1, 0)) {
+		return errors.New("Inventory Target must be between 0 and 1")
+	}
+	if m.Skew.Sign() < 0 || m.MaxSkew.Sign() < 0 || m.SizeReduction.Sign() < 0 {
+		return errors.New("Inventory Skew, MaxSkew and SizeReduction cannot be negative")
+	}
+	return nil
+}
+
+func inventoryConfig() inventoryModel {
+	if bots.Inventory == nil {
+		return defaultInventory
+	}
+	return *bots.Inventory
+}
+
+// inventorySkew - price shift for a base coin share of inventory, positive when holding too much base
+func inventorySkew(inventory decimal.Decimal) decimal.Decimal {
+	m := inventoryConfig()
+	skew := inventory.Sub(m.Target).Mul(m.Skew)
+	if m.MaxSkew.IsZero() {
+		return skew
+	}
+	return decimal.Max(decimal.Min(skew, m.MaxSkew), m.MaxSkew.Neg())
+}
+
+// inventorySize - factor applied to the order amount of side, less than one if the order moves the
+// inventory further away from the target
+func inventorySize(side string, inventory decimal.Decimal) decimal.Decimal {
+	m := inventoryConfig()
+	deviation := inventory.Sub(m.Target)
+	if (side == "buy" && deviation.Sign() <= 0) || (side == "sell" && deviation.Sign() >= 0) {
+		return decimal.New(1, 0)
+	}
+	return decimal.Max(decimal.New(1, 0).Sub(deviation.Abs().Mul(m.SizeReduction)), decimal.Zero)
+}
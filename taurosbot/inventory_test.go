@@ -0,0 +1,30 @@
+package taurosbot //trading-bot
+
+import "testing"
+
+func TestInventorySkew(t *testing.T) {
+	defer func() { bots.Inventory = nil }()
+	tests := []struct {
+		name                     string
+		skew, maxSkew, inventory string
+		want                     string
+	}{
+		{"no max skew", "0.02", "0", "0.9", "0.008"},
+		{"no max skew short", "0.02", "0", "0.1", "-0.008"},
+		{"below max skew", "0.02", "0.01", "0.9", "0.008"},
+		{"clamped", "0.02", "0.005", "0.9", "0.005"},
+		{"clamped short", "0.02", "0.005", "0.1", "-0.005"},
+		{"on target", "0.02", "0.005", "0.5", "0"},
+		{"no skew", "0", "0.005", "0.9", "0"},
+	}
+	for _, tt := range tests {
+		bots.Inventory = &inventoryModel{Target: dec("0.5"), Skew: dec(tt.skew), MaxSkew: dec(tt.maxSkew)}
+		if got := inventorySkew(dec(tt.inventory)); !got.Equal(dec(tt.want)) {
+			t.Errorf("%s: inventorySkew(%s) = %s, want %s", tt.name, tt.inventory, got, tt.want)
+		}
+	}
+	bots.Inventory = nil
+	if got := inventorySkew(dec("0.9")); !got.IsZero() {
+		t.Errorf("default inventory skew = %s, want 0", got)
+	}
+}
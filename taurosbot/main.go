@@ -1,20 +1,21 @@
-package main //trading-bot
+package taurosbot //trading-bot
 
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io/ioutil"
-	"math/rand"
 	"os"
 	"os/signal"
-	"strconv"
 	"strings"
 	"sync"
 	"syscall"
 	"time"
 
+	"git.vmo.mx/Tauros/tradingbot/exchange"
+	"git.vmo.mx/Tauros/tradingbot/grpcconf"
 	pb "git.vmo.mx/Tauros/tradingbot/proto"
 	"github.com/shopspring/decimal"
 	log "github.com/sirupsen/logrus"
@@ -25,14 +26,15 @@ import (
 
 // myOrder to keep track of bot orders
 type myOrder struct {
+	Bot    int //index of the bot in the configuration
 	Side   string
-	Price  string
-	Amount string
+	Price  decimal.Decimal
+	Amount decimal.Decimal
 }
 
 var myOrders struct {
 	sync.RWMutex
-	orders map[int64]*myOrder
+	orders map[exchange.OrderID]*myOrder
 }
 
 type obItem struct {
@@ -41,12 +43,11 @@ type obItem struct {
 }
 
 type bot struct {
-	Side        string    //"buy" or "sell"
-	Spread      float64   //how deep must the bid go for it to find the price
-	Pct         float64   //percentage of the available balance that should be put in order
-	MinInterval int       //minimum milliseconds to change
-	MaxInterval int       //maximum milliseconds to change
-	Quit        chan bool //channel used to stop the bot
+	Side        string          //"buy" or "sell"
+	Spread      decimal.Decimal //how deep must the bid go for it to find the price
+	Pct         decimal.Decimal //percentage of the available balance that should be put in order
+	MinInterval int             //minimum milliseconds to change
+	MaxInterval int             //maximum milliseconds to change
 }
 
 type credentials struct {
@@ -59,6 +60,9 @@ type credentials struct {
 		BaseAPIUrl string `json:"base_api_url"`
 		BalService string `json:"bal_service"`
 		BalPort string `json:"bal_port"`
+		WatchdogToken string `json:"watchdog_token"`
+		WithdrawalWhitelist map[string][]string `json:"withdrawal_whitelist"` //only addresses each coin can be withdrawn to
+		ControlToken string `json:"control_token"`
 	} `json:"tauros"`
 	OpenExchangeRates struct {
 		Token string `json:"token"`
@@ -66,50 +70,80 @@ type credentials struct {
 	Gdax struct {
 		APIToken string `json:"api_token"`
 	} `json:"gdax"`
+	Grpc struct {
+		GdaxToken string `json:"gdax_token"`
+		OxToken string `json:"ox_token"`
+		BalToken string `json:"bal_token"`
+	} `json:"grpc"`
 }
 
 // bots configuration loaded from file
 var bots struct {
-	Market           string
-	Bots             []bot
-	Testing          bool
-	TaurosToken      string
-	TestingToken     string
-	CoinbaseToken    string
-	LogLevel         string
-	BuyPct           float64
-	SellPct          float64
-	Spread           float64
-	ExchangeModifier float64
-	Email            string
-	Password         string
+	Market             string
+	Bots               []bot
+	Testing            bool
+	TaurosToken        string
+	TestingToken       string
+	WatchdogToken      string
+	CoinbaseToken      string
+	LogLevel           string
+	BuyPct             decimal.Decimal
+	SellPct            decimal.Decimal
+	Spread             decimal.Decimal
+	ExchangeModifier   decimal.Decimal
+	Email              string
+	Password           string
+	Exchange           string            //venue where the bots place their orders, "tauros" if empty
+	AuditLog           string            //file where every order placement, cancellation and withdrawal request to Tauros is appended, empty to disable
+	PriceSources       []priceSourceConf //reference price sources, only the gdax service if empty
+	MaxSourceDeviation decimal.Decimal   //sources further than this fraction from the median are left out
+	MinSources         int               //minimum sources that must agree to quote
+	SpreadModel        *spreadModel      //volatility adaptive spread, constant Spread if nil
+	Inventory          *inventoryModel   //target inventory and skew, no skew around half and half if nil
+	ControlAddr        string            //address of the control endpoint, e.g. ":2230", disabled if empty
+	ControlToken       string            //token required by the control endpoint, from the credentials
+	Risk               *riskLimits       //risk limits, none if nil
+	ReconcileInterval  int               //seconds between order reconciliations with the exchange, 30 if 0
+	StateFile          string            //file where the bots orders, fills and halt are kept across restarts, empty to disable
+	MaxFailures        int               //failed cycles in a row after which a bot pulls its orders, 5 if 0
+	ShutdownTimeout    int               //seconds to stop the bots and cancel their orders on exit, 30 if 0
+	WatchdogTimeout    int               //seconds without a bot heartbeat before all the market orders are cancelled, disabled if 0
+	GdaxAddr           string            //address of the gdax service, "gdax:2222" if empty
+	OxAddr             string            //address of the openxrate service, "ox:2223" if empty
+	BalAddr            string            //address of the balances service, bal_service:bal_port of the credentials if empty
+	GrpcTLS            grpcconf.TLS      //TLS of the connections to the services, plain text if empty
+	GdaxToken          string
+	OxToken            string
+	BalToken           string
 }
 
 // all current market data in a struct to be able to mux lock and lock
 var marketData struct {
 	sync.RWMutex
-	currentAsk          float64
-	currentBid          float64
-	currentExchangeRate float64
-	imbalance           float64
-	buyBalance          float64
-	sellBalance         float64
+	currentAsk          decimal.Decimal
+	currentBid          decimal.Decimal
+	currentExchangeRate decimal.Decimal
+	inventory           decimal.Decimal //share of the balances in the base coin
+	skew                decimal.Decimal //price shift towards the target inventory
+	spread              decimal.Decimal //last effective spread
+	mid                 decimal.Decimal //reference mid price, in the quote coin of the reference
+	buyBalance          decimal.Decimal
+	sellBalance         decimal.Decimal
 }
 
 var balPort string
 var balService string
+var grpcTLS grpcconf.TLS
 var gdaxMarket string
 var buySide string
 var sellSide string
 var tauMarket string
-var quitExchangeRater chan bool
+var venue exchange.Exchange
 var gdaxDone chan bool
 var wg sync.WaitGroup
 var grpcGdaxConn *grpc.ClientConn
 var grpcOxConn *grpc.ClientConn
 var grpcBalConn *grpc.ClientConn
-var getTicker = pb.NewTickerServiceClient(grpcGdaxConn)
-var getSpreadPrice = pb.NewSpreadPriceServiceClient(grpcGdaxConn)
 var getOxRate = pb.NewOxServiceClient(grpcOxConn)
 var getTauBalances = pb.NewBalancesServiceClient(grpcBalConn)
 
@@ -145,226 +179,431 @@ func loadCredentialsFile(filename string) {
 	}
 	bots.TaurosToken = creds.Tauros.Token
 	bots.TestingToken = creds.Tauros.TestingToken
+	bots.WatchdogToken = creds.Tauros.WatchdogToken
+	bots.ControlToken = grpcconf.Env("TB_CONTROL_TOKEN", creds.Tauros.ControlToken)
+	tau.SetWithdrawalWhitelist(creds.Tauros.WithdrawalWhitelist)
 	bots.CoinbaseToken = creds.Gdax.APIToken
+	bots.GdaxToken = grpcconf.Env("TB_GDAX_TOKEN", creds.Grpc.GdaxToken)
+	bots.OxToken = grpcconf.Env("TB_OX_TOKEN", creds.Grpc.OxToken)
+	bots.BalToken = grpcconf.Env("TB_BAL_TOKEN", creds.Grpc.BalToken)
 	balService = creds.Tauros.BalService
-	balPort = "2224" //creds.Tauros.BalPort
+	balPort = creds.Tauros.BalPort
+	if balPort == "" {
+		balPort = "2224"
+	}
 }
 
-func getExchangeRate() {
+func getExchangeRate() error {
 	res, err := getOxRate.GetOxRate(context.Background(), &pb.OxRequest{Currency: "MXN"})
 	if err != nil {
-		log.Fatalf("Unable to get exchange rate from ox grpc service: %v", err)
+		return fmt.Errorf("getExchangeRate-> %v", err)
 	}
-	m, err := strconv.ParseFloat(res.Rate, 64)
+	m, err := decimal.NewFromString(res.Rate)
 	if err != nil {
-		log.Errorf("Bad Rate %s unable to convert to float64: %v", res.Rate, err)
+		return fmt.Errorf("getExchangeRate-> bad Rate %s: %v", res.Rate, err)
 	}
 	marketData.Lock()
-	marketData.currentExchangeRate = m * bots.ExchangeModifier
-	log.Infof("current exchange rate = %f", marketData.currentExchangeRate)
+	marketData.currentExchangeRate = m.Mul(bots.ExchangeModifier)
+	log.Infof("current exchange rate = %s", marketData.currentExchangeRate)
 	marketData.Unlock()
+	return nil
+}
+
+func getRefTicker() (maxBid, minAsk decimal.Decimal, err error) {
+	maxBid, minAsk, err = refPrice.Ticker()
+	if err != nil {
+		return maxBid, minAsk, fmt.Errorf("getRefTicker-> %s: %v", refPrice.Name(), err)
+	}
+	return maxBid, minAsk, nil
+}
+
+func getDepthPrice(side string, depth decimal.Decimal) (decimal.Decimal, error) { //todo: refactor all naming "spread" to "depth"
+	price, err := refPrice.DepthPrice(side, depth)
+	if err != nil {
+		return price, fmt.Errorf("getDepthPrice-> %s: %v", refPrice.Name(), err)
+	}
+	return price, nil
 }
 
-func getGdaxTicker() (maxBid, minAsk decimal.Decimal) {
-	res, err := getTicker.GetTicker(context.Background(), &pb.TickerRequest{Market: gdaxMarket})
+func getBalances() (buyBal, sellBal decimal.Decimal, err error) {
+	res, err := getTauBalances.GetBalances(context.Background(), &pb.BalancesRequest{Market: bots.Market})
+	if err != nil {
+		return buyBal, sellBal, fmt.Errorf("getBalances-> %v", err)
+	}
+	sellAvailable, err := decimal.NewFromString(res.Left.Available)
 	if err != nil {
-		log.Fatalf("Unable to get ticker from gdax grpc service: %v", err)
+		return buyBal, sellBal, fmt.Errorf("getBalances-> bad Left.Available %s: %v", res.Left.Available, err)
 	}
-	var mb, ma decimal.Decimal
-	mb, err = decimal.NewFromString(res.MaxBid)
+	sellFrozen, err := decimal.NewFromString(res.Left.Frozen)
 	if err != nil {
-		log.Fatalf("Bad Ticker MaxBid, unable %s to convert to decimal: %v", res.MaxBid, err)
+		return buyBal, sellBal, fmt.Errorf("getBalances-> bad Left.Frozen %s: %v", res.Left.Frozen, err)
 	}
-	ma, err = decimal.NewFromString(res.MinAsk)
+	buyAvailable, err := decimal.NewFromString(res.Right.Available)
 	if err != nil {
-		log.Fatalf("Bad Ticker MinAsk, unable to convert %s to decimal:%v", res.MinAsk, err)
+		return buyBal, sellBal, fmt.Errorf("getBalances-> bad Right.Available %s: %v", res.Right.Available, err)
 	}
-	return mb, ma
+	buyFrozen, err := decimal.NewFromString(res.Right.Frozen)
+	if err != nil {
+		return buyBal, sellBal, fmt.Errorf("getBalances-> bad Right.Frozen %s: %v", res.Right.Frozen, err)
+	}
+	//log.Infof("grpcBal result - buyAvailable=%s buyFrozen=%s, sellAvailable=%s sellFrozen=%s",buyAvailable,buyFrozen,sellAvailable,sellFrozen)
+	return buyAvailable.Add(buyFrozen), sellAvailable.Add(sellFrozen), nil //todo: this result should come from the grpc service itself
 }
 
-func getDepthPrice(side string, depth float64) float64 { //todo: refactor all naming "spread" to "depth"
-	res, err := getSpreadPrice.GetSpreadPrice(context.Background(), &pb.SpreadPriceRequest{
-		Market: gdaxMarket,
-		Side:   side,
-		Depth:  fmt.Sprintf("%f", depth),
-	})
+// updateBalances refreshes the balances, reference mid price and inventory of marketData, it takes the marketData lock
+func updateBalances() error {
+	buyAvailable, sellAvailable, err := getBalances()
 	if err != nil {
-		log.Fatalf("Unable to get depth price from gdax grpc service: %v", err)
+		return fmt.Errorf("updateBalances-> %v", err)
 	}
-	price, err := strconv.ParseFloat(res.Price, 64)
+	maxBid, minAsk, err := getRefTicker()
 	if err != nil {
-		log.Fatalf("Bad Depth Price, unable to convert %s to float64: %v", res.Price, err)
+		return fmt.Errorf("updateBalances-> %v", err)
 	}
-	return price
+	marketData.Lock()
+	defer marketData.Unlock()
+	if marketData.currentExchangeRate.IsZero() {
+		return errors.New("updateBalances-> current exchange rate cannot be zero")
+	}
+	price := decimal.Avg(maxBid, minAsk)
+	marketData.mid = price
+	buyAvailable = buyAvailable.Div(price.Mul(marketData.currentExchangeRate))
+	buyBalance := buyAvailable.Mul(bots.BuyPct)
+	sellBalance := sellAvailable.Mul(bots.SellPct)
+	inventory := inventoryConfig().Target
+	if total := buyAvailable.Add(sellAvailable); total.Sign() > 0 {
+		inventory = sellAvailable.Div(total)
+	}
+	skew := inventorySkew(inventory)
+	if !marketData.inventory.Equal(inventory) || !marketData.skew.Equal(skew) {
+		log.Infof("Inventory: %s of the balances in %s, target %s, skew %s", inventory.StringFixed(4), buySide, inventoryConfig().Target, skew)
+		marketData.inventory = inventory
+		marketData.skew = skew
+	}
+	if !marketData.buyBalance.Equal(buyBalance) {
+		log.Infof("Old buyBalance: %s, New buybalance: %s", marketData.buyBalance, buyBalance)
+		marketData.buyBalance = buyBalance
+	}
+	if !marketData.sellBalance.Equal(sellBalance) {
+		log.Infof("Old sellbalance: %s, new sellbalance: %s", marketData.sellBalance, sellBalance)
+		marketData.sellBalance = sellBalance
+	}
+	return nil
 }
 
-func getBalances() (buyBal, sellBal float64) {
-	res, err := getTauBalances.GetBalances(context.Background(), &pb.BalancesRequest{Market: bots.Market})
+// fitOrder rounds the price to the market tick and the amount to the market lot, and resizes
+// the order up to the market minimum if the available balance allows it
+func fitOrder(side string, amount, price, available decimal.Decimal) (decimal.Decimal, decimal.Decimal, error) {
+	market, err := venue.MarketRules(tauMarket)
 	if err != nil {
-		log.Fatalf("Unable to get balances from balances grpc service: %v", err)
+		return amount, price, fmt.Errorf("unable to get %s trading rules: %v", tauMarket, err)
 	}
-	sellAvailable, err:=strconv.ParseFloat(res.Left.Available, 64)
-	if err!= nil {
-		log.Fatalf("Bad Left.Available, unable to convert %s to float64: %v", res.Left.Available, err)
+	price = market.RoundPrice(side, price)
+	amount = market.RoundAmount(amount)
+	if min := market.MinOrderAmount(price); amount.LessThan(min) {
+		if min.GreaterThan(available) {
+			return amount, price, fmt.Errorf("amount %s at price %s is below the market minimum %s and only %s is available", amount, price, min, available)
+		}
+		log.Infof("Resizing %s order from %s to the market minimum %s", side, amount, min)
+		amount = min
 	}
-	sellFrozen, err := strconv.ParseFloat(res.Left.Frozen, 64)
-	if err!= nil {
-		log.Fatalf("Bad Left.Frozen, unable to convert %s to float64: %v", res.Left.Frozen, err)
+	return amount, price, market.CheckOrder(amount, price)
+}
+
+// tauBookTTL - how long checkTauBook reuses the Tauros order book, it is only used for logging
+var tauBookTTL = 5 * time.Second
+
+var tauBook struct {
+	sync.Mutex
+	book    tau.OrderBook
+	updated time.Time
+}
+
+// getTauBook - the Tauros order book of the market, asked again once it is older than tauBookTTL
+func getTauBook() (tau.OrderBook, error) {
+	tauBook.Lock()
+	defer tauBook.Unlock()
+	if time.Since(tauBook.updated) < tauBookTTL {
+		return tauBook.book, nil
 	}
-	buyAvailable, err := strconv.ParseFloat(res.Right.Available, 64)
-	if err!= nil {
-		log.Fatalf("Bad Right.Available, unable to convert %s to float64: %v", res.Right.Available, err)
+	book, err := tau.GetOrderBook(tauMarket)
+	if err != nil {
+		return book, err
 	}
-	buyFrozen, err := strconv.ParseFloat(res.Right.Frozen, 64)
-	if err!= nil {
-		log.Fatalf("Bad Right.Frozen, unable to convert %s to float64: %v", res.Right.Frozen, err)
+	tauBook.book, tauBook.updated = book, time.Now()
+	return book, nil
+}
+
+// checkTauBook logs where an order at price would sit in the Tauros order book, leaving out the bot own orders
+func checkTauBook(side string, price decimal.Decimal) {
+	book, err := getTauBook()
+	if err != nil {
+		log.Warnf("Unable to get Tauros %s order book: %v", tauMarket, err)
+		return
+	}
+	var own []tau.Order
+	myOrders.RLock()
+	for _, o := range myOrders.orders {
+		own = append(own, tau.Order{Side: o.Side, Price: o.Price, Amount: o.Amount})
+	}
+	myOrders.RUnlock()
+	book = book.Without(own)
+	bid, hasBid := book.BestBid()
+	ask, hasAsk := book.BestAsk()
+	if hasBid && hasAsk {
+		log.Debugf("Tauros %s bid %s ask %s spread %s", tauMarket, bid.Price, ask.Price, ask.Price.Sub(bid.Price))
+	}
+	switch {
+	case side == "buy" && hasAsk && price.GreaterThanOrEqual(ask.Price):
+		log.Warnf("Buy order at %s crosses the Tauros ask at %s", price, ask.Price)
+	case side == "sell" && hasBid && price.LessThanOrEqual(bid.Price):
+		log.Warnf("Sell order at %s crosses the Tauros bid at %s", price, bid.Price)
+	case side == "buy" && (!hasBid || price.GreaterThan(bid.Price)):
+		log.Debugf("Buy order at %s is top of book", price)
+	case side == "sell" && (!hasAsk || price.LessThan(ask.Price)):
+		log.Debugf("Sell order at %s is top of book", price)
 	}
-	//log.Infof("grpcBal result - buyAvailable=%f buyFrozen=%f, sellAvailable=%f sellFrozen=%f",buyAvailable,buyFrozen,sellAvailable,sellFrozen)
-	return buyAvailable+buyFrozen, sellAvailable+sellFrozen //todo: this result should come from the grpc service itself
 }
 
-func updateBalances() {
-	buyAvailable, sellAvailable := getBalances()
-	if marketData.currentExchangeRate == 0.0 {
-			log.Fatalf("Update Balances -> Current Exchange Rate cannot be zero")
+// newVenue - the exchange where the bots place their orders, as set in the bots configuration
+func newVenue() (exchange.Exchange, error) {
+	switch bots.Exchange {
+	case "", "tauros":
+		return exchange.NewTauros(), nil
+	case "fake":
+		return newPaperVenue()
 	}
-	maxBid, minAsk := getGdaxTicker()
-	price, _ := decimal.Avg(maxBid, minAsk).Float64()
-	buyAvailable = buyAvailable/ (price * marketData.currentExchangeRate)
-	marketData.imbalance = 0.5
-	if sellAvailable > 0.0 {
-		marketData.imbalance = buyAvailable*bots.BuyPct / (buyAvailable*bots.BuyPct + sellAvailable*bots.SellPct)
+	return nil, fmt.Errorf("newVenue-> unknown exchange %s", bots.Exchange)
+}
+
+// newPaperVenue - in memory exchange with the Tauros market rules and the balances of the balances
+// service, for paper trading: the bots quote as usual but their orders never reach Tauros or fill
+func newPaperVenue() (*exchange.Fake, error) {
+	rules, err := exchange.NewTauros().MarketRules(tauMarket)
+	if err != nil {
+		return nil, fmt.Errorf("newPaperVenue-> %v", err)
 	}
-	if marketData.buyBalance != buyAvailable*bots.BuyPct {
-		log.Infof("Old buyBalance: %f, New buybalance: %f", marketData.buyBalance, buyAvailable*bots.BuyPct)
-		marketData.buyBalance = buyAvailable*bots.BuyPct
+	quote, base, err := getBalances()
+	if err != nil {
+		return nil, fmt.Errorf("newPaperVenue-> %v", err)
 	}
-	if marketData.sellBalance != sellAvailable*bots.SellPct {
-		log.Infof("Old sellbalance: %f, new sellbalance: %f", marketData.sellBalance,sellAvailable*bots.SellPct)
-		marketData.sellBalance = sellAvailable*bots.SellPct
+	paper := exchange.NewFake(rules)
+	paper.SetBalance(buySide, base)
+	paper.SetBalance(sellSide, quote)
+	log.Warnf("Paper trading %s: orders are not sent to Tauros", tauMarket)
+	return paper, nil
+}
+
+// logOrderState asks the exchange what happened to an order that is no longer open
+func logOrderState(orderID exchange.OrderID) {
+	order, err := venue.GetOrder(orderID)
+	if err != nil {
+		log.Errorf("Unable to get status of order #%s: %v", orderID, err)
+		return
+	}
+	log.Infof("Order #%s is %s: filled %s, %s left at %s", orderID, order.State, order.Filled, order.Amount, order.Price)
+}
+
+// logFills logs the fills of the bots orders as the exchange reports them
+func logFills(fills <-chan exchange.Fill) {
+	for f := range fills {
+		log.Infof("Fill of order #%s: %s %s at %s, fee %s %s", f.OrderID, f.Side, f.Amount, f.Price, f.Fee, f.FeeCoin)
+		addFill(f)
 	}
 }
 
-func addOrder(orderID int64, amount string, side string, price string) int64 {
+// addOrder replaces the bot order orderID with a new one, returning the id of the order the bot keeps
+func addOrder(bot int, orderID exchange.OrderID, amount decimal.Decimal, side string, price decimal.Decimal) (exchange.OrderID, error) {
 	var err error
 	myOrders.Lock()
 	defer myOrders.Unlock()
-	o := fmt.Sprintf("side=%s price=%s amount=%s orderID=%d", side, price, amount, orderID)
+	o := fmt.Sprintf("side=%s price=%s amount=%s orderID=%s", side, price, amount, orderID)
 
 	//check order parameters
-	log.Tracef("Adding order side=%s price=%s amount=%s orderID=%d", side, price, amount, orderID)
-	a, _ := strconv.ParseFloat(amount, 64)
-	if a<=0.0 { 
-		log.Errorf("Cannot place an order with amount 0 or negative: %s"+o)
-		return orderID
-	}
-	p, _ := strconv.ParseFloat(price, 64)
-	if p<=0.0 {
-		log.Errorf("Cannot place an order with price 0 or negative: %s"+o)
-	}
-	if (p*a<=5.0) && (sellSide=="mxn")  { //todo: get minimum amount order from Tauros API
-		log.Warnf("Cannot place an order of less than $5 pesos: %s"+o)
-  }
-	
+	log.Tracef("Adding order %s", o)
+	if amount.Sign() <= 0 {
+		log.Errorf("Cannot place an order with amount 0 or negative: %s", o)
+		return orderID, nil
+	}
+	if price.Sign() <= 0 {
+		log.Errorf("Cannot place an order with price 0 or negative: %s", o)
+		return orderID, nil
+	}
 	//check if this order is already posted
 	for _, o := range myOrders.orders {
-		if o.Price == price && o.Side == side && o.Amount == amount {
-			log.Tracef("Order %d did not change skipping", orderID)
-			return orderID
+		if o.Price.Equal(price) && o.Side == side && o.Amount.Equal(amount) {
+			log.Tracef("Order %s did not change skipping", orderID)
+			return orderID, nil
 		}
 	}
 	//check if this order will cause a self trade, if so cancel opposing orders before posting
 	for i, o := range myOrders.orders {
 		//log.Infof("side=%4s o.Side=%4s price=%s, o.Price=%s id=%d", side, o.Side, price, o.Price, i)
-		if (side == "buy" && o.Side == "sell" && price >= o.Price) || (side == "sell" && o.Side == "buy" && price <= o.Price) {
-			log.Infof("Preventing self trade - closing order #%d", i)
-			if err := tau.CloseOrder(i); err != nil {
-				log.Errorf("Unable to delete possible self trade order #%d - %v", i, err)
+		if (side == "buy" && o.Side == "sell" && price.GreaterThanOrEqual(o.Price)) || (side == "sell" && o.Side == "buy" && price.LessThanOrEqual(o.Price)) {
+			log.Infof("Preventing self trade - closing order #%s", i)
+			if err := venue.CancelOrder(i); err != nil {
+				//left for the reconciler to find out what happened to it
+				log.Errorf("Unable to delete possible self trade order #%s - %v", i, err)
+				continue
 			}
-			delete(myOrders.orders, i)
+			removeOrder(i)
 		}
 	}
 	//delete old bot order in current orderbooks before adding a new one
-	if orderID != 0 && myOrders.orders[orderID] != nil {
-		delete(myOrders.orders, orderID)
-		if err:=tau.CloseOrder(orderID); err != nil {
-			log.Errorf("Unable to delete previous bot order #%d, %v, %s", orderID,err,o)
-			//this can happen if a trade was filled.
+	if orderID != "" && myOrders.orders[orderID] != nil {
+		if err := venue.CancelOrder(orderID); err != nil {
+			//this can happen if a trade was filled, the reconciler drops it once it is not open
+			log.Errorf("Unable to delete previous bot order #%s, %v, %s", orderID, err, o)
+			logOrderState(orderID)
+		} else {
+			removeOrder(orderID)
+		}
+	}
+	if err := checkOrderRisk(side, price, amount, myOrders.orders); err != nil {
+		log.Errorf("Not placing order %s: %v", o, err)
+		if haltQuoting(err.Error()) {
+			log.Errorf("Risk limit breached, halting the bots and cancelling their orders: %v", err)
+			go cancelBotOrders() //it needs the myOrders lock
 		}
+		return "", nil
 	}
 	log.Infof("New order %s", o)
-	orderID, err = tau.PlaceOrder(tau.Message{
-		Market: tauMarket,
-		Amount: amount,
-		Side:   side,
-		Type:   "limit",
-		Price:  price,
-	})
+	orderID, err = venue.PlaceOrder(tauMarket, side, amount, price)
 	if err != nil {
-		log.Fatalf("Unable to place new order %s: %v buyBalance=%f, sellBalance=%f",o,err,marketData.buyBalance,marketData.sellBalance)
+		return "", fmt.Errorf("addOrder-> unable to place new order %s: %v", o, err)
 	}
+	orderPlaced()
 	//keep track of all orders made
-	myOrders.orders[orderID] = &myOrder{
+	setOrder(orderID, &myOrder{
+		Bot:    bot,
 		Side:   side,
 		Price:  price,
 		Amount: amount,
-	}
-	return orderID
+	})
+	return orderID, nil
 }
 
-func runBot(b bot) {
-	log.Infof("Starting bot: side %4s, spread %f, pct %f, interval %d-%d ...", b.Side, b.Spread, b.Pct, b.MinInterval, b.MaxInterval)
-	var orderID int64
-	var available, price float64
-	var orderAmount, orderSide, orderPrice string
-	if b.MinInterval >= b.MaxInterval {
-		log.Fatalf("MinInterval (%d) cannot be greater than MaxInterval (%d)", b.MinInterval, b.MaxInterval)
-	}
-	ticker := time.NewTicker(time.Duration(b.MinInterval+rand.Intn(b.MaxInterval-b.MinInterval)) * time.Millisecond)
+// runBot quotes one side of the market as bot id of the configuration, taking over orderID if it is
+// not empty, until ctx is done. A failed cycle is retried with a growing interval and the bot pulls
+// its orders after MaxFailures failed cycles in a row
+func runBot(ctx context.Context, id int, b bot, orderID exchange.OrderID) {
+	log.Infof("Starting bot: side %4s, spread %s, pct %s, interval %d-%d ...", b.Side, b.Spread, b.Pct, b.MinInterval, b.MaxInterval)
+	failures := 0
+	ticker := time.NewTicker(botInterval(b, failures))
+	heartbeat(id)
 	for {
 		select {
 		case <-ticker.C:
-			ticker.Stop()
-			marketData.RLock()
-			updateBalances()
-			if b.Side == "buy" {
-				available = marketData.buyBalance 
-				if available > 0.0 {
-					price = getDepthPrice("buy", b.Spread) * marketData.currentExchangeRate * (1 - (bots.Spread * marketData.imbalance))
-					orderAmount = fmt.Sprintf("%.8f", available*b.Pct)
-					orderSide = "buy"
-					orderPrice = fmt.Sprintf("%.8f", price)
-				} else {
-					log.Warn("no balance available for buying %s",buySide)
+			if halted, reason := riskHalted(); halted {
+				log.Debugf("%s bot halted: %s", b.Side, reason)
+				heartbeat(id)
+				ticker.Stop()
+				ticker = time.NewTicker(botInterval(b, failures))
+				continue
+			}
+			var err error
+			if orderID, err = botCycle(id, b, orderID); err != nil {
+				failures++
+				log.Errorf("%s bot %d cycle failed %d times in a row: %v", b.Side, id, failures, err)
+				if failures == maxFailures() {
+					log.Errorf("%s bot %d pulling its orders after %d failures", b.Side, id, failures)
+					pullBotOrders(id)
+					orderID = ""
 				}
 			} else {
-				available = marketData.sellBalance
-				if available > 0.0 {
-					price = getDepthPrice("sell", b.Spread) * marketData.currentExchangeRate * (1 + (bots.Spread * (1 - marketData.imbalance)))
-					orderAmount = fmt.Sprintf("%.8f", available*b.Pct)
-					orderSide = "sell"
-					orderPrice = fmt.Sprintf("%.8f", price)
-				} else {
-					log.Warn("no balance available for selling %s",sellSide)
-				}
+				failures = 0
 			}
-			marketData.RUnlock()
-			orderID = addOrder(orderID, orderAmount, orderSide, orderPrice)
-			ticker = time.NewTicker(time.Duration(b.MinInterval+rand.Intn(b.MaxInterval-b.MinInterval)) * time.Millisecond)
-		case <-b.Quit:
+			heartbeat(id)
 			ticker.Stop()
-			log.Infof("Stopping bot: side %4s, spread %f, pct %f, interval %d-%d ...", b.Side, b.Spread, b.Pct, b.MinInterval, b.MaxInterval)
-			if orderID != 0 {
-				if tau.CloseOrder(orderID) != nil {
-					log.Warnf("Unable to close order #%d", orderID)
+			ticker = time.NewTicker(botInterval(b, failures))
+		case <-ctx.Done():
+			ticker.Stop()
+			log.Infof("Stopping bot: side %4s, spread %s, pct %s, interval %d-%d ...", b.Side, b.Spread, b.Pct, b.MinInterval, b.MaxInterval)
+			if orderID != "" {
+				if err := venue.CancelOrder(orderID); err != nil {
+					//kept in myOrders so the shutdown tries again
+					log.Warnf("Unable to close order #%s: %v", orderID, err)
+				} else {
+					myOrders.Lock()
+					removeOrder(orderID)
+					myOrders.Unlock()
 				}
-				myOrders.Lock()
-				delete(myOrders.orders, orderID)
-				myOrders.Unlock()
 			}
-			wg.Done()
+			return
+		}
+	}
+}
+
+// botCycle computes the order of the bot and places it instead of orderID
+func botCycle(id int, b bot, orderID exchange.OrderID) (exchange.OrderID, error) {
+	spread := effectiveSpread()
+	if bots.SpreadModel != nil {
+		log.Infof("%s bot effective spread %s", b.Side, spread)
+	}
+	orderAmount, orderPrice, available, err := botQuote(b, spread)
+	if err != nil {
+		return orderID, fmt.Errorf("botCycle-> %v", err)
+	}
+	if orderAmount.Sign() > 0 {
+		if orderAmount, orderPrice, err = fitOrder(b.Side, orderAmount, orderPrice, available); err != nil {
+			log.Warnf("Skipping %s order: %v", b.Side, err)
+			orderAmount = decimal.Zero
+		}
+	}
+	if orderAmount.Sign() <= 0 {
+		//no quote, the old order would stay on the book at a stale price
+		if orderID != "" {
+			log.Infof("Pulling %s bot %d order #%s, it has no quote", b.Side, id, orderID)
+			pullBotOrders(id)
+		}
+		return botOrder(id), nil
+	}
+	if venue.Name() == "tauros" {
+		checkTauBook(b.Side, orderPrice)
+	}
+	return addOrder(id, orderID, orderAmount, b.Side, orderPrice)
+}
+
+// botQuote - amount and price of the bot order, zero if there is no balance for it
+func botQuote(b bot, spread decimal.Decimal) (amount, price, available decimal.Decimal, err error) {
+	one := decimal.New(1, 0)
+	if err = updateBalances(); err != nil {
+		return amount, price, available, fmt.Errorf("botQuote-> %v", err)
+	}
+	marketData.Lock()
+	marketData.spread = spread
+	rate, inventory, shift := marketData.currentExchangeRate, marketData.inventory, marketData.skew
+	buyBalance, sellBalance := marketData.buyBalance, marketData.sellBalance
+	marketData.Unlock()
+	half := spread.Div(decimal.New(2, 0))
+	if b.Side == "buy" {
+		available = buyBalance
+		if available.Sign() <= 0 {
+			log.Warnf("no balance available for buying %s", buySide)
+			return amount, price, available, nil
+		}
+		depth, err := getDepthPrice("buy", b.Spread)
+		if err != nil {
+			return amount, price, available, fmt.Errorf("botQuote-> %v", err)
 		}
+		skew := one.Sub(half).Sub(shift)
+		price = depth.Mul(rate).Mul(skew)
+		amount = available.Mul(b.Pct).Mul(inventorySize("buy", inventory))
+		return amount, price, available, nil
 	}
+	available = sellBalance
+	if available.Sign() <= 0 {
+		log.Warnf("no balance available for selling %s", sellSide)
+		return amount, price, available, nil
+	}
+	depth, err := getDepthPrice("sell", b.Spread)
+	if err != nil {
+		return amount, price, available, fmt.Errorf("botQuote-> %v", err)
+	}
+	skew := one.Add(half).Sub(shift)
+	price = depth.Mul(rate).Mul(skew)
+	amount = available.Mul(b.Pct).Mul(inventorySize("sell", inventory))
+	return amount, price, available, nil
 }
 
 // logformatter.Format this is needed because the log outputs incorrectly in Docker-Compose
@@ -377,53 +616,126 @@ func (f *logFormatter) Format(entry *log.Entry) ([]byte, error) {
 	return []byte(fmt.Sprintf("%s %s %s\n", f.LevelDesc[entry.Level], timestamp, entry.Message)), nil
 }
 
-func main() {
+// Dial connects to the gdax, openxrate and balances grpc services at addr sending token in every
+// request, replaced by the all in one command to reach its in-process services
+var Dial = dialService
+
+// dialService connects to a grpc service at addr with the TLS of the bots configuration and token
+func dialService(addr, token string) (*grpc.ClientConn, error) {
+	opts, err := grpcconf.DialOptions(grpcTLS, token)
+	if err != nil {
+		return nil, fmt.Errorf("dialService-> %v", err)
+	}
+	return grpc.Dial(addr, opts...)
+}
+
+// endpoints of the services given by flags, they override the environment and the bots configuration
+var endpointFlags struct {
+	gdax, ox, bal string
+	tls           grpcconf.TLS
+}
+
+// Main runs the bots of the bots and credentials files given as arguments and exits
+func Main() {
+	flag.StringVar(&endpointFlags.gdax, "gdax", "", "address of the gdax service, env TB_GDAX_ADDR, GdaxAddr of the bots file or gdax:2222")
+	flag.StringVar(&endpointFlags.ox, "ox", "", "address of the openxrate service, env TB_OX_ADDR, OxAddr of the bots file or ox:2223")
+	flag.StringVar(&endpointFlags.bal, "bal", "", "address of the balances service, env TB_BAL_ADDR, BalAddr of the bots file or bal_service:bal_port of the credentials")
+	endpointFlags.tls.Flags(flag.CommandLine, "grpc-", "TB_GRPC")
 	flag.Parse()
+	os.Exit(Run(flag.Arg(0), flag.Arg(1)))
+}
+
+// setEndpoints resolves the service addresses and the TLS of the connections, flags first, then
+// environment, then the bots configuration
+func setEndpoints() {
+	bots.GdaxAddr = grpcconf.Endpoint(endpointFlags.gdax, "TB_GDAX_ADDR", bots.GdaxAddr, "gdax:2222")
+	bots.OxAddr = grpcconf.Endpoint(endpointFlags.ox, "TB_OX_ADDR", bots.OxAddr, "ox:2223")
+	bots.BalAddr = grpcconf.Endpoint(endpointFlags.bal, "TB_BAL_ADDR", bots.BalAddr, balService+":"+balPort)
+	grpcTLS = bots.GrpcTLS
+	if endpointFlags.tls.Enabled() {
+		grpcTLS = endpointFlags.tls
+	}
+}
+
+// Run starts the bots of botsFile with the credentials of credentialsFile and returns the exit code
+// once they are shut down by SIGINT or SIGTERM
+func Run(botsFile, credentialsFile string) int {
 	logFormatter := new(logFormatter)
 	logFormatter.TimestampFormat = "2006-01-02 15:04:05"
 	logFormatter.LevelDesc = []string{"PANIC", "FATAL", "ERROR", "WARNI", "INFOR", "DEBUG","TRACE"}
 	log.SetFormatter(logFormatter)
-	myOrders.orders = make(map[int64]*myOrder)
+	myOrders.orders = make(map[exchange.OrderID]*myOrder)
 
-	loadBotsFile(flag.Arg(0))
-	loadCredentialsFile(flag.Arg(1))
+	loadBotsFile(botsFile)
+	loadCredentialsFile(credentialsFile)
 
-	log.Info("Subscribing to gdax service at gdax:2222")
-	grpcGdaxConn, err := grpc.Dial("gdax:2222", grpc.WithInsecure())
+	setEndpoints()
+	log.Infof("Subscribing to gdax service at %s (TLS %t)", bots.GdaxAddr, grpcTLS.Enabled())
+	grpcGdaxConn, err := Dial(bots.GdaxAddr, bots.GdaxToken)
 	if err != nil {
-		log.Fatalf("Unable to connect to GDAX grpc service at localhost:2222")
+		log.Fatalf("Unable to connect to GDAX grpc service at %s: %v", bots.GdaxAddr, err)
 	}
 	defer grpcGdaxConn.Close()
-	getTicker = pb.NewTickerServiceClient(grpcGdaxConn)
-	getSpreadPrice = pb.NewSpreadPriceServiceClient(grpcGdaxConn)
+	getCandles = pb.NewTradesServiceClient(grpcGdaxConn)
 
-	log.Info("Subscribing to openexchange service at ox:2223")
-	grpcOxConn, err := grpc.Dial("ox:2223", grpc.WithInsecure())
+	log.Infof("Subscribing to openexchange service at %s", bots.OxAddr)
+	grpcOxConn, err := Dial(bots.OxAddr, bots.OxToken)
 	if err != nil {
-		log.Fatalf("Unable to connect to Ox grpc service at localhost:2223")
+		log.Fatalf("Unable to connect to Ox grpc service at %s: %v", bots.OxAddr, err)
 	}
 	defer grpcOxConn.Close() //probably not needed
 	getOxRate = pb.NewOxServiceClient(grpcOxConn)
 
-	log.Info("subscribing to balance service at "+balService+":"+balPort)
-	grpcBalConn, err := grpc.Dial(balService+":"+balPort, grpc.WithInsecure())
+	log.Infof("Subscribing to balance service at %s", bots.BalAddr)
+	grpcBalConn, err := Dial(bots.BalAddr, bots.BalToken)
 	if err != nil {
-		log.Fatalf("Unable to connect to Bal grpc service at localhost:%s",balPort)
+		log.Fatalf("Unable to connect to Bal grpc service at %s: %v", bots.BalAddr, err)
 	}
 	getTauBalances = pb.NewBalancesServiceClient(grpcBalConn)
 
+	if bots.SpreadModel != nil {
+		if err := checkSpreadModel(bots.SpreadModel); err != nil {
+			log.Fatalf("Bad bots configuration: %v", err)
+		}
+		log.Infof("Volatility spread model: %+v", *bots.SpreadModel)
+	}
+	if bots.Inventory != nil {
+		if err := checkInventoryModel(bots.Inventory); err != nil {
+			log.Fatalf("Bad bots configuration: %v", err)
+		}
+	}
+	log.Infof("Inventory model: %+v", inventoryConfig())
+	for i, b := range bots.Bots {
+		if b.MinInterval >= b.MaxInterval {
+			log.Fatalf("Bad bots configuration: bot %d MinInterval (%d) must be less than MaxInterval (%d)", i, b.MinInterval, b.MaxInterval)
+		}
+	}
+	if bots.WatchdogTimeout > 0 {
+		if err := checkWatchdog(time.Duration(bots.WatchdogTimeout) * time.Second); err != nil {
+			log.Fatalf("Bad bots configuration: %v", err)
+		}
+	}
 	log.Printf("bots file loglevel =%s", bots.LogLevel)
 	if loglevel, err := (log.ParseLevel(bots.LogLevel)); err != nil {
 		log.Warn(`Incorrect LogLevel especified, must be "Panic", "Fatal", "Error", "Warn", "Info", "Debug" or "Trace"`)
 	} else {
 		log.SetLevel(loglevel)
 	}
-	log.Infof("Testing: %t TestingToken: %s TaurosToken: %s",bots.Testing,bots.TestingToken,bots.TaurosToken)
+	log.Infof("Testing: %t", bots.Testing)
 	if bots.Testing {
 		tau.Init(true, bots.TestingToken)
 	} else {
 		tau.Init(false, bots.TaurosToken)
 	}
+	if bots.AuditLog != "" {
+		auditLog, err := tau.OpenAuditLog(bots.AuditLog)
+		if err != nil {
+			log.Fatalf("Unable to open audit log: %v", err)
+		}
+		defer auditLog.Close()
+		tau.SetAuditHook(auditLog.Record)
+		log.Infof("Auditing Tauros order and withdrawal requests to %s", bots.AuditLog)
+	}
 	//todo: check total pct of buy and sell is <=1.0
 	m := strings.Split(bots.Market, "-")
 	buySide = strings.ToLower(m[0])
@@ -436,52 +748,137 @@ func main() {
 	}
 
 	log.Printf("Market = %s buySide = %s sellSide = %s", bots.Market, buySide, sellSide)
-	getExchangeRate()
-	log.Infof("Exchange rate is %f", marketData.currentExchangeRate)
+	for _, conn := range setupPriceSources(grpcGdaxConn) {
+		defer conn.Close()
+	}
+	if venue, err = newVenue(); err != nil {
+		log.Fatalf("Unable to set up the exchange: %v", err)
+	}
+	defer venue.Close()
+	market, err := venue.MarketRules(tauMarket)
+	if err != nil {
+		log.Fatalf("Unable to get %s trading rules from %s: %v", tauMarket, venue.Name(), err)
+	}
+	log.Infof("Market rules: tick %s, lot %s, min amount %s, min value %s", market.TickSize, market.LotSize, market.MinAmount, market.MinValue)
+	for retry := time.Second; ; retry *= 2 {
+		err := getExchangeRate()
+		if err == nil {
+			break
+		}
+		if retry > maxBackoff {
+			retry = maxBackoff
+		}
+		log.Errorf("Unable to get the exchange rate, retrying in %s: %v", retry, err)
+		time.Sleep(retry)
+	}
+	log.Infof("Exchange rate is %s", marketData.currentExchangeRate)
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
 	log.Info("Launching Exchange Rate updater")
-	quitExchangeRater = make(chan bool, 2)
+	wg.Add(1)
 	go func() {
+		defer wg.Done()
 		ticker := time.NewTicker(time.Duration(5) * time.Minute)
+		defer ticker.Stop()
 		for {
 			select {
 			case <-ticker.C:
-				getExchangeRate()
-			case <-quitExchangeRater:
-				ticker.Stop()
+				if err := getExchangeRate(); err != nil {
+					log.Errorf("Unable to update the exchange rate, keeping the last one: %v", err)
+				}
+			case <-ctx.Done():
 				log.Info("stopping exchange rate updater")
-				wg.Done()
+				return
 			}
 		}
 	}()
 
 	log.Info("Ok, starting bots")
-	if err := tau.CloseAllOrders(); err != nil {
-		log.Errorf("Tauros Error closing all orders: %v", err)
+	owned := make(map[int]exchange.OrderID)
+	if bots.StateFile != "" {
+		if err := openState(bots.StateFile); err != nil {
+			log.Fatalf("Unable to restore the bots state: %v", err)
+		}
+		wg.Add(1)
+		go runSnapshots(ctx, time.Minute)
+		//orders closed while down are dropped and orders not in the state are cancelled
+		reconcileOrders()
+		owned = adoptOrders(bots.Bots)
+	} else {
+		results, err := venue.CancelOrders(tauMarket, "")
+		if err != nil {
+			log.Errorf("%s Error closing %s orders: %v", venue.Name(), tauMarket, err)
+		} else if err := exchange.CancelErrors(results); err != nil {
+			log.Errorf("%s Error closing %s orders: %v", venue.Name(), tauMarket, err)
+		}
+	}
+	fills, err := venue.Fills(tauMarket)
+	if err != nil {
+		log.Errorf("Unable to follow %s fills: %v", tauMarket, err)
+	} else {
+		go logFills(fills)
 	}
 
-	// start bots
-	for i := range bots.Bots { //first set up quit channel
-		bots.Bots[i].Quit = make(chan bool)
+	reconcileInterval := 30 * time.Second
+	if bots.ReconcileInterval > 0 {
+		reconcileInterval = time.Duration(bots.ReconcileInterval) * time.Second
+	}
+	wg.Add(1)
+	go runReconciler(ctx, reconcileInterval)
+
+	if bots.ControlAddr != "" {
+		startControl(bots.ControlAddr, bots.ControlToken)
+	}
+
+	if bots.WatchdogTimeout > 0 && venue.Name() != "tauros" {
+		log.Warnf("Watchdog disabled, it cancels Tauros orders and the bots trade on %s", venue.Name())
+	} else if bots.WatchdogTimeout > 0 {
+		timeout := time.Duration(bots.WatchdogTimeout) * time.Second
+		token := bots.WatchdogToken
+		if token == "" {
+			log.Warn("No watchdog_token in the credentials, the watchdog uses the bots api token")
+			token = bots.TaurosToken
+			if bots.Testing {
+				token = bots.TestingToken
+			}
+		}
+		log.Infof("Watchdog cancels all %s orders after %s without a bot heartbeat", tauMarket, timeout)
+		wg.Add(1)
+		go runWatchdog(ctx, timeout, token)
 	}
 
+	// start bots
 	for i, b := range bots.Bots {
 		log.Infof("starting bot %d", i)
-		go runBot(b)
+		wg.Add(1)
+		go superviseBot(ctx, i, b, owned[i])
 	}
 
+	resume := make(chan os.Signal, 1)
+	signal.Notify(resume, syscall.SIGUSR1)
+	go func() {
+		for range resume {
+			resumeBots()
+		}
+	}()
+
 	c := make(chan os.Signal, 2)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
-	<-c
-	log.Warnf("SIGTERM received, ending Tauros trading bots...")
-//	log.SetLevel(log.TraceLevel)
-	for i := range bots.Bots {
-		wg.Add(1)
-		log.Infof("quitting bot %d", i)
-		bots.Bots[i].Quit <- true
+	code := 0
+	select {
+	case sig := <-c:
+		log.Warnf("%s received, ending Tauros trading bots...", sig)
+	case reason := <-exitRequests:
+		log.Errorf("Ending Tauros trading bots: %s", reason)
+		code = 1
+	}
+	left, err := shutdown(stop)
+	if err != nil {
+		log.Errorf("Unable to cancel the bots orders: %v", err)
+		code = 1
+	} else if len(left) > 0 {
+		log.Errorf("Unable to cancel %d orders in %s: %v", len(left), shutdownTimeout(), left)
+		code = 1
 	}
-
-	log.Info("quitting exchange rater...")
-	wg.Add(1)
-	quitExchangeRater <- true
-	wg.Wait() //not working?
+	return code
 }
@@ -0,0 +1,671 @@
+package taurosbot //trading-bot
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"git.vmo.mx/Tauros/tradingbot/exchange"
+	pb "git.vmo.mx/Tauros/tradingbot/proto"
+	tau "git.vmo.mx/Tauros/tradingbot/taurosapi"
+	"git.vmo.mx/Tauros/tradingbot/taurosapi/taurosfake"
+	"github.com/shopspring/decimal"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+)
+
+const testToken = "test-token"
+
+func TestMain(m *testing.M) {
+	log.SetLevel(log.WarnLevel)
+	os.Exit(m.Run())
+}
+
+func dec(s string) decimal.Decimal {
+	d, err := decimal.NewFromString(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// testSource - reference price source with fixed prices that can be made to fail
+type testSource struct {
+	sync.Mutex
+	bid, ask decimal.Decimal
+	err      error
+}
+
+func (s *testSource) Name() string {
+	return "test"
+}
+
+func (s *testSource) Ticker() (maxBid, minAsk decimal.Decimal, err error) {
+	s.Lock()
+	defer s.Unlock()
+	return s.bid, s.ask, s.err
+}
+
+func (s *testSource) DepthPrice(side string, depth decimal.Decimal) (decimal.Decimal, error) {
+	s.Lock()
+	defer s.Unlock()
+	if side == "buy" {
+		return s.bid, s.err
+	}
+	return s.ask, s.err
+}
+
+func (s *testSource) fail(err error) {
+	s.Lock()
+	s.err = err
+	s.Unlock()
+}
+
+// testBalances - balances service answering with the balances of the venue of the bots
+type testBalances struct{}
+
+func (testBalances) GetBalances(ctx context.Context, in *pb.BalancesRequest, opts ...grpc.CallOption) (*pb.Balances, error) {
+	balances, err := venue.Balances()
+	if err != nil {
+		return nil, err
+	}
+	left, right := balances[buySide], balances[sellSide]
+	return &pb.Balances{
+		Left:  &pb.Balance{Available: left.Available.String(), Frozen: left.Frozen.String()},
+		Right: &pb.Balance{Available: right.Available.String(), Frozen: right.Frozen.String()},
+	}, nil
+}
+
+// resetBots clears the bots state and makes them quote btc-mxn on v around a 100-101 reference at 20 mxn
+func resetBots(v exchange.Exchange) *testSource {
+	venue = v
+	bots.Market = "btc-mxn"
+	bots.BuyPct, bots.SellPct = decimal.New(1, 0), decimal.New(1, 0)
+	bots.Spread = decimal.Zero
+	bots.SpreadModel, bots.Inventory, bots.Risk = nil, nil, nil
+	bots.MaxFailures = 0
+	buySide, sellSide, tauMarket = "btc", "mxn", "btc-mxn"
+	myOrders.Lock()
+	myOrders.orders = make(map[exchange.OrderID]*myOrder)
+	myOrders.Unlock()
+	risk.Lock()
+	risk.halted, risk.exiting, risk.reason, risk.placed = false, false, "", nil
+	risk.Unlock()
+	closedOrders.Lock()
+	closedOrders.orders = nil
+	closedOrders.Unlock()
+	tauBook.Lock()
+	tauBook.updated = time.Time{}
+	tauBook.Unlock()
+	marketData.Lock()
+	marketData.currentExchangeRate = decimal.New(20, 0)
+	marketData.Unlock()
+	getTauBalances = testBalances{}
+	source := &testSource{bid: decimal.New(100, 0), ask: decimal.New(101, 0)}
+	refPrice = source
+	state = nil
+	return source
+}
+
+// startPaper points the bots to an in memory exchange with the btc-mxn rules of taurosfake, 10 btc and 1000000 mxn
+func startPaper() (*exchange.Fake, *testSource) {
+	paper := exchange.NewFake(exchange.TauRules(taurosfake.DefaultMarkets[0]))
+	paper.SetBalance("btc", decimal.New(10, 0))
+	paper.SetBalance("mxn", decimal.New(1000000, 0))
+	return paper, resetBots(paper)
+}
+
+// startTauros starts a taurosfake server with 10 btc and 1000000 mxn and points the bots to it
+func startTauros() (*taurosfake.Server, *testSource) {
+	fake := taurosfake.New(testToken)
+	tau.Init(false, testToken)
+	tau.SetURL(fake.URL)
+	fake.SetBalance("btc", decimal.New(10, 0))
+	fake.SetBalance("mxn", decimal.New(1000000, 0))
+	return fake, resetBots(exchange.NewTauros())
+}
+
+func tauID(t *testing.T, id exchange.OrderID) int64 {
+	n, err := strconv.ParseInt(string(id), 10, 64)
+	if err != nil {
+		t.Fatalf("bad order id %q: %v", id, err)
+	}
+	return n
+}
+
+func trackedOrders() map[exchange.OrderID]myOrder {
+	myOrders.RLock()
+	defer myOrders.RUnlock()
+	orders := make(map[exchange.OrderID]myOrder, len(myOrders.orders))
+	for id, o := range myOrders.orders {
+		orders[id] = *o
+	}
+	return orders
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestAddOrderPlacesAndReplaces(t *testing.T) {
+	fake, _ := startTauros()
+	defer fake.Close()
+
+	id, err := addOrder(0, "", dec("0.5"), "buy", dec("1900"))
+	if err != nil || id == "" {
+		t.Fatalf("addOrder = %q, %v", id, err)
+	}
+	open := fake.OpenOrders()
+	if len(open) != 1 || !open[0].Price.Equal(dec("1900")) || !open[0].Amount.Equal(dec("0.5")) {
+		t.Fatalf("open orders = %+v, want a buy of 0.5 at 1900", open)
+	}
+
+	requests := len(fake.Requests())
+	same, err := addOrder(0, id, dec("0.5"), "buy", dec("1900"))
+	if err != nil || same != id {
+		t.Fatalf("unchanged addOrder = %q, %v, want %q", same, err, id)
+	}
+	if n := len(fake.Requests()); n != requests {
+		t.Errorf("unchanged order sent %d requests", n-requests)
+	}
+
+	newID, err := addOrder(0, id, dec("0.6"), "buy", dec("1910"))
+	if err != nil || newID == "" || newID == id {
+		t.Fatalf("replacing addOrder = %q, %v", newID, err)
+	}
+	if old, _ := fake.Order(tauID(t, id)); old.IsOpen {
+		t.Errorf("replaced order #%s is still open", id)
+	}
+	open = fake.OpenOrders()
+	if len(open) != 1 || !open[0].Price.Equal(dec("1910")) {
+		t.Errorf("open orders = %+v, want a buy at 1910", open)
+	}
+	tracked := trackedOrders()
+	if len(tracked) != 1 || tracked[newID].Side != "buy" || !tracked[newID].Amount.Equal(dec("0.6")) {
+		t.Errorf("tracked orders = %+v, want only #%s", tracked, newID)
+	}
+}
+
+func TestAddOrderPreventsSelfTrade(t *testing.T) {
+	fake, _ := startTauros()
+	defer fake.Close()
+
+	buy, err := addOrder(0, "", dec("0.5"), "buy", dec("1900"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sell, err := addOrder(1, "", dec("0.5"), "sell", dec("1950"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.OpenOrders()) != 2 {
+		t.Fatalf("a sell above the buy closed it: %+v", fake.OpenOrders())
+	}
+
+	cross, err := addOrder(1, sell, dec("0.5"), "sell", dec("1890"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o, _ := fake.Order(tauID(t, buy)); o.IsOpen {
+		t.Errorf("buy #%s crossed by the new sell is still open", buy)
+	}
+	open := fake.OpenOrders()
+	if len(open) != 1 || open[0].Side != "sell" || !open[0].Price.Equal(dec("1890")) {
+		t.Errorf("open orders = %+v, want only the sell at 1890", open)
+	}
+	if tracked := trackedOrders(); len(tracked) != 1 || tracked[cross].Side != "sell" {
+		t.Errorf("tracked orders = %+v, want only #%s", tracked, cross)
+	}
+}
+
+func TestAddOrderFaults(t *testing.T) {
+	t.Run("5xx placing", func(t *testing.T) {
+		fake, _ := startTauros()
+		defer fake.Close()
+		fake.FailNext(1, 503)
+		id, err := addOrder(0, "", dec("0.5"), "buy", dec("1900"))
+		if err == nil || id != "" {
+			t.Fatalf("addOrder = %q, %v, want an error", id, err)
+		}
+		if len(trackedOrders()) != 0 || len(fake.OpenOrders()) != 0 {
+			t.Errorf("failed order is tracked or open")
+		}
+	})
+
+	t.Run("5xx cancelling", func(t *testing.T) {
+		fake, _ := startTauros()
+		defer fake.Close()
+		id, err := addOrder(0, "", dec("0.5"), "buy", dec("1900"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		fake.FailNext(1, 500)
+		newID, err := addOrder(0, id, dec("0.5"), "buy", dec("1910"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		tracked := trackedOrders()
+		_, kept := tracked[id]
+		_, placed := tracked[newID]
+		if !kept || !placed {
+			t.Errorf("tracked orders = %+v, want #%s left for the reconciler and #%s", tracked, id, newID)
+		}
+		if len(fake.OpenOrders()) != 2 {
+			t.Errorf("open orders = %+v, want both", fake.OpenOrders())
+		}
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		fake, _ := startTauros()
+		defer fake.Close()
+		fake.SetInvalidToken(true)
+		id, err := addOrder(0, "", dec("0.5"), "buy", dec("1900"))
+		if err == nil || id != "" {
+			t.Fatalf("addOrder = %q, %v, want an error", id, err)
+		}
+		if len(trackedOrders()) != 0 {
+			t.Errorf("rejected order is tracked")
+		}
+	})
+
+	t.Run("partial fill", func(t *testing.T) {
+		fake, _ := startTauros()
+		defer fake.Close()
+		fake.SetPartialFill(dec("0.5"))
+		fake.AddBookOrder("btc-mxn", "sell", dec("1900"), dec("1"))
+		id, err := addOrder(0, "", dec("0.4"), "buy", dec("1900"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if o, _ := fake.Order(tauID(t, id)); !o.IsOpen || !o.Amount.Equal(dec("0.2")) {
+			t.Fatalf("order = %+v, want 0.2 left open", o)
+		}
+		reconcileOrders()
+		if tracked := trackedOrders(); !tracked[id].Amount.Equal(dec("0.2")) {
+			t.Errorf("tracked amount after reconciling = %s, want 0.2", tracked[id].Amount)
+		}
+	})
+
+	t.Run("latency", func(t *testing.T) {
+		fake, _ := startTauros()
+		defer fake.Close()
+		fake.SetLatency(200 * time.Millisecond)
+		start := time.Now()
+		id, err := addOrder(0, "", dec("0.5"), "buy", dec("1900"))
+		if err != nil || id == "" {
+			t.Fatalf("addOrder = %q, %v", id, err)
+		}
+		if d := time.Since(start); d < 200*time.Millisecond {
+			t.Errorf("addOrder took %s, the latency was not applied", d)
+		}
+		if len(fake.OpenOrders()) != 1 {
+			t.Errorf("open orders = %+v, want one", fake.OpenOrders())
+		}
+	})
+}
+
+func TestRunBotQuotesAndCancelsOnStop(t *testing.T) {
+	fake, _ := startTauros()
+	defer fake.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan bool)
+	go func() {
+		runBot(ctx, 0, bot{Side: "buy", Pct: dec("0.01"), MinInterval: 10, MaxInterval: 20}, "")
+		close(done)
+	}()
+	waitFor(t, "the bot order", func() bool { return len(fake.OpenOrders()) == 1 })
+	o := fake.OpenOrders()[0]
+	//reference bid 100 at 20 mxn without spread or skew
+	if o.Side != "buy" || !o.Price.Equal(dec("2000")) {
+		t.Errorf("bot order = %+v, want a buy at 2000", o)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("bot did not stop")
+	}
+	if open := fake.OpenOrders(); len(open) != 0 {
+		t.Errorf("open orders after stopping = %+v", open)
+	}
+	if tracked := trackedOrders(); len(tracked) != 0 {
+		t.Errorf("tracked orders after stopping = %+v", tracked)
+	}
+}
+
+func TestRunBotPullsOrdersAfterFailures(t *testing.T) {
+	fake, source := startTauros()
+	defer fake.Close()
+	bots.MaxFailures = 2
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go runBot(ctx, 0, bot{Side: "sell", Pct: dec("0.1"), MinInterval: 10, MaxInterval: 20}, "")
+	waitFor(t, "the bot order", func() bool { return len(fake.OpenOrders()) == 1 })
+
+	source.fail(errors.New("reference down"))
+	waitFor(t, "the bot to pull its order", func() bool { return len(fake.OpenOrders()) == 0 })
+	if tracked := trackedOrders(); len(tracked) != 0 {
+		t.Errorf("tracked orders after pulling = %+v", tracked)
+	}
+}
+
+func TestLoadCredentialsWithdrawalWhitelist(t *testing.T) {
+	fake, _ := startTauros()
+	defer fake.Close()
+	f, err := ioutil.TempFile("", "credentials")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString(`{"tauros": {"token": "test-token", "withdrawal_whitelist": {"btc": ["bc1qwhitelisted"]}}}`)
+	f.Close()
+	loadCredentialsFile(f.Name())
+	defer tau.SetWithdrawalWhitelist(nil)
+
+	if _, err := tau.Withdraw("btc", "bc1qwhitelisted", dec("0.01")); err != nil {
+		t.Errorf("Withdraw to the whitelisted address: %v", err)
+	}
+	if _, err := tau.Withdraw("btc", "bc1qother", dec("0.01")); err == nil || !strings.Contains(err.Error(), tau.ErrNotWhitelisted.Error()) {
+		t.Errorf("Withdraw to another address error = %v, want %v", err, tau.ErrNotWhitelisted)
+	}
+}
+
+func TestCheckTauBookIsCached(t *testing.T) {
+	fake, _ := startTauros()
+	defer fake.Close()
+	books := func() int {
+		n := 0
+		for _, r := range fake.Requests() {
+			if strings.Contains(r, "trading/orderbook") {
+				n++
+			}
+		}
+		return n
+	}
+	checkTauBook("buy", dec("1900"))
+	checkTauBook("sell", dec("2100"))
+	if n := books(); n != 1 {
+		t.Errorf("%d order book requests within tauBookTTL, want 1", n)
+	}
+	tauBook.Lock()
+	tauBook.updated = time.Now().Add(-tauBookTTL)
+	tauBook.Unlock()
+	checkTauBook("buy", dec("1900"))
+	if n := books(); n != 2 {
+		t.Errorf("%d order book requests after tauBookTTL, want 2", n)
+	}
+}
+
+func TestAddOrderOnFakeExchange(t *testing.T) {
+	paper, _ := startPaper()
+
+	id, err := addOrder(0, "", dec("0.5"), "sell", dec("2100"))
+	if err != nil || id == "" {
+		t.Fatalf("addOrder = %q, %v", id, err)
+	}
+	newID, err := addOrder(0, id, dec("0.5"), "sell", dec("2110"))
+	if err != nil || newID == id {
+		t.Fatalf("replacing addOrder = %q, %v", newID, err)
+	}
+	if o, _ := paper.GetOrder(id); o.State != exchange.OrderCancelled {
+		t.Errorf("replaced order state = %s", o.State)
+	}
+
+	paper.Fill(newID, dec("0.2"))
+	reconcileOrders()
+	if tracked := trackedOrders(); !tracked[newID].Amount.Equal(dec("0.3")) {
+		t.Errorf("tracked amount after a partial fill = %s, want 0.3", tracked[newID].Amount)
+	}
+	paper.Fill(newID, dec("0.3"))
+	reconcileOrders()
+	if tracked := trackedOrders(); len(tracked) != 0 {
+		t.Errorf("tracked orders after the fill = %+v", tracked)
+	}
+	closedOrders.RLock()
+	closed := closedOrders.orders
+	closedOrders.RUnlock()
+	if len(closed) != 1 || closed[0].ID != newID || closed[0].State != exchange.OrderFilled {
+		t.Errorf("closed orders = %+v, want #%s filled", closed, newID)
+	}
+
+	paper.Err = errors.New("exchange down")
+	if id, err := addOrder(0, "", dec("0.5"), "sell", dec("2100")); err == nil || id != "" {
+		t.Errorf("addOrder with the exchange down = %q, %v", id, err)
+	}
+}
+
+func TestRunBotOnFakeExchange(t *testing.T) {
+	paper, _ := startPaper()
+	open := func() []exchange.Order {
+		o, _ := paper.OpenOrders("btc-mxn")
+		return o
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan bool)
+	go func() {
+		runBot(ctx, 0, bot{Side: "sell", Pct: dec("0.1"), MinInterval: 10, MaxInterval: 20}, "")
+		close(done)
+	}()
+	waitFor(t, "the bot order", func() bool { return len(open()) == 1 })
+	//reference ask 101 at 20 mxn, 10% of the 10 btc
+	if o := open()[0]; o.Side != "sell" || !o.Price.Equal(dec("2020")) || !o.Amount.Equal(dec("1")) {
+		t.Errorf("bot order = %+v, want a sell of 1 at 2020", o)
+	}
+	cancel()
+	<-done
+	if o := open(); len(o) != 0 {
+		t.Errorf("open orders after stopping = %+v", o)
+	}
+}
+
+func TestNewVenue(t *testing.T) {
+	fake, _ := startTauros()
+	defer fake.Close()
+
+	bots.Exchange = "fake"
+	defer func() { bots.Exchange = "" }()
+	v, err := newVenue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Name() != "fake" {
+		t.Fatalf("venue = %s, want fake", v.Name())
+	}
+	rules, err := v.MarketRules("btc-mxn")
+	if err != nil || !rules.TickSize.Equal(dec("0.01")) || !rules.MinValue.Equal(dec("5")) {
+		t.Errorf("paper rules = %+v, %v, want the Tauros btc-mxn rules", rules, err)
+	}
+	balances, _ := v.Balances()
+	if !balances["btc"].Available.Equal(dec("10")) || !balances["mxn"].Available.Equal(dec("1000000")) {
+		t.Errorf("paper balances = %+v, want the balances service ones", balances)
+	}
+	if _, err := v.PlaceOrder("btc-mxn", "buy", dec("1"), dec("2000")); err != nil {
+		t.Errorf("paper order: %v", err)
+	}
+	if len(fake.OpenOrders()) != 0 {
+		t.Errorf("paper order reached Tauros: %+v", fake.OpenOrders())
+	}
+
+	bots.Exchange = "nasdaq"
+	if _, err := newVenue(); err == nil {
+		t.Errorf("unknown exchange did not fail")
+	}
+}
+
+// marketsFixture - Tauros data/listmarkets response with the xrp-mxn rules: tick 0.0001, lot 0.01, min amount 1 and min value 10
+const marketsFixture = `{"success": true, "data": {"markets": [
+	{"name": "XRP-MXN", "left_coin": "XRP", "right_coin": "MXN", "price_decimals": 4, "amount_decimals": 2,
+		"min_amount": "1", "min_value": "10", "maker_fee": "0.0025", "taker_fee": "0.005"}
+]}}`
+
+func TestFitOrder(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/data/listmarkets/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(marketsFixture))
+	}))
+	defer srv.Close()
+	tau.Init(false, testToken)
+	tau.SetURL(srv.URL)
+	resetBots(exchange.NewTauros())
+	tauMarket = "xrp-mxn"
+
+	tests := []struct {
+		name                           string
+		side, amount, price, available string
+		wantAmount, wantPrice, wantErr string
+	}{
+		{"rounded buy", "buy", "10.129", "5.12345", "100", "10.12", "5.1234", ""},
+		{"rounded sell", "sell", "10.129", "5.12341", "100", "10.12", "5.1235", ""},
+		{"resized to the min value", "buy", "1.5", "5", "100", "2", "5", ""},
+		{"resized to the min amount", "sell", "0.5", "20", "5", "1", "20", ""},
+		{"min value not available", "buy", "1.5", "5", "1.9", "1.5", "5", "below the market minimum 2"},
+		{"min amount not available", "sell", "0.5", "20", "0.8", "0.5", "20", "below the market minimum 1"},
+	}
+	for _, tt := range tests {
+		amount, price, err := fitOrder(tt.side, dec(tt.amount), dec(tt.price), dec(tt.available))
+		if !amount.Equal(dec(tt.wantAmount)) || !price.Equal(dec(tt.wantPrice)) {
+			t.Errorf("%s: fitOrder = %s at %s, want %s at %s", tt.name, amount, price, tt.wantAmount, tt.wantPrice)
+		}
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("%s: fitOrder error %v", tt.name, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("%s: fitOrder error = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+
+	tauMarket = "zzz-mxn"
+	if _, _, err := fitOrder("buy", dec("1"), dec("1"), dec("1")); err == nil {
+		t.Errorf("fitOrder of an unknown market did not fail")
+	}
+}
+
+// stubBalances - balances service answering with fixed available balances
+type stubBalances struct {
+	left, right string
+}
+
+func (s stubBalances) GetBalances(ctx context.Context, in *pb.BalancesRequest, opts ...grpc.CallOption) (*pb.Balances, error) {
+	return &pb.Balances{Left: &pb.Balance{Available: s.left, Frozen: "0"}, Right: &pb.Balance{Available: s.right, Frozen: "0"}}, nil
+}
+
+func TestBotCycleSkippedQuotePullsOrder(t *testing.T) {
+	tests := []struct {
+		name, btc string
+	}{
+		{"no balance", "0"},
+		{"below the market minimum", "0.001"}, //the 5 mxn minimum value needs 0.0025 btc at 2020
+	}
+	for _, tt := range tests {
+		paper, _ := startPaper()
+		b := bot{Side: "sell", Pct: dec("0.1")}
+		orderID, err := botCycle(0, b, "")
+		if err != nil || orderID == "" {
+			t.Fatalf("%s: first botCycle = %q, %v", tt.name, orderID, err)
+		}
+		getTauBalances = stubBalances{left: tt.btc, right: "1000000"}
+		if orderID, err = botCycle(0, b, orderID); err != nil || orderID != "" {
+			t.Errorf("%s: botCycle without a quote = %q, %v, want the order pulled", tt.name, orderID, err)
+		}
+		if open, _ := paper.OpenOrders("btc-mxn"); len(open) != 0 {
+			t.Errorf("%s: open orders = %+v, want none", tt.name, open)
+		}
+		if tracked := trackedOrders(); len(tracked) != 0 {
+			t.Errorf("%s: tracked orders = %+v, want none", tt.name, tracked)
+		}
+	}
+}
+
+// constBalances - balances service with fixed balances, without locks so the race detector sees the bots ones only
+type constBalances struct{}
+
+func (constBalances) GetBalances(ctx context.Context, in *pb.BalancesRequest, opts ...grpc.CallOption) (*pb.Balances, error) {
+	return &pb.Balances{Left: &pb.Balance{Available: "10", Frozen: "0"}, Right: &pb.Balance{Available: "1000000", Frozen: "0"}}, nil
+}
+
+// constSource - reference price source with fixed prices, without locks
+type constSource struct{}
+
+func (constSource) Name() string {
+	return "const"
+}
+
+func (constSource) Ticker() (maxBid, minAsk decimal.Decimal, err error) {
+	return decimal.New(100, 0), decimal.New(101, 0), nil
+}
+
+func (constSource) DepthPrice(side string, depth decimal.Decimal) (decimal.Decimal, error) {
+	return decimal.New(100, 0), nil
+}
+
+func TestBotQuoteConcurrent(t *testing.T) {
+	startPaper()
+	getTauBalances, refPrice = constBalances{}, constSource{}
+	var quotes sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		side := []string{"buy", "sell"}[i%2]
+		quotes.Add(1)
+		go func() {
+			defer quotes.Done()
+			for j := 0; j < 20; j++ {
+				amount, price, _, err := botQuote(bot{Side: side, Pct: dec("0.1")}, dec("0.01"))
+				if err != nil || amount.Sign() <= 0 || price.Sign() <= 0 {
+					t.Errorf("%s botQuote = %s at %s, %v", side, amount, price, err)
+					return
+				}
+			}
+		}()
+	}
+	quotes.Wait()
+	marketData.RLock()
+	defer marketData.RUnlock()
+	if !marketData.spread.Equal(dec("0.01")) || !marketData.mid.Equal(dec("100.5")) {
+		t.Errorf("marketData spread %s mid %s, want 0.01 and 100.5", marketData.spread, marketData.mid)
+	}
+}
+
+func TestRunBotKeepsOrderItCannotCancel(t *testing.T) {
+	paper, _ := startPaper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan bool)
+	go func() {
+		runBot(ctx, 0, bot{Side: "buy", Pct: dec("0.01"), MinInterval: 10, MaxInterval: 20}, "")
+		close(done)
+	}()
+	waitFor(t, "the bot order", func() bool { return len(trackedOrders()) == 1 })
+
+	risk.Lock()
+	risk.exiting = true //no more cycles, like a shutdown
+	risk.Unlock()
+	paper.Err = errors.New("exchange down")
+	cancel()
+	<-done
+	paper.Err = nil
+	if n := len(trackedOrders()); n != 1 {
+		t.Fatalf("%d orders tracked after failing to cancel, want the bot order kept", n)
+	}
+	if left, err := shutdown(func() {}); err != nil || len(left) != 0 {
+		t.Errorf("orders left after the shutdown = %v, %v", left, err)
+	}
+}
@@ -0,0 +1,142 @@
+package taurosbot //trading-bot
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"git.vmo.mx/Tauros/tradingbot/exchange"
+	"github.com/shopspring/decimal"
+	log "github.com/sirupsen/logrus"
+)
+
+// closedOrder - a bot order that is no longer open, as found by the reconciler
+type closedOrder struct {
+	ID       exchange.OrderID `json:"id"`
+	Side     string           `json:"side"`
+	Price    decimal.Decimal  `json:"price"`
+	Amount   decimal.Decimal  `json:"amount"`
+	Filled   decimal.Decimal  `json:"filled"`
+	State    string           `json:"state"`
+	ClosedAt time.Time        `json:"closed_at"`
+}
+
+// closedOrdersSize - closed orders kept for the control endpoint
+var closedOrdersSize = 100
+
+var closedOrders struct {
+	sync.RWMutex
+	orders []closedOrder
+}
+
+// recordClosed journals and keeps a closed order, the caller may hold the myOrders lock
+func recordClosed(o closedOrder) {
+	closedOrders.Lock()
+	journal(stateRecord{Op: "closed", Closed: &o})
+	appendClosed(o)
+	closedOrders.Unlock()
+}
+
+// appendClosed keeps the last closedOrdersSize closed orders, the caller holds the closedOrders lock
+func appendClosed(o closedOrder) {
+	closedOrders.orders = append(closedOrders.orders, o)
+	if len(closedOrders.orders) > closedOrdersSize {
+		closedOrders.orders = closedOrders.orders[len(closedOrders.orders)-closedOrdersSize:]
+	}
+}
+
+// runReconciler reconciles myOrders with the exchange every interval until ctx is done
+func runReconciler(ctx context.Context, interval time.Duration) {
+	defer wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			reconcileOrders()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// reconcileOrders makes myOrders match the open orders of the market in the exchange: orders the
+// bots do not know about are cancelled, bot orders that are no longer open are dropped and recorded
+// as filled or cancelled. The exchange is asked without holding the myOrders lock
+func reconcileOrders() {
+	open, err := venue.OpenOrders(tauMarket)
+	if err != nil {
+		log.Warnf("Reconciler: unable to get %s open orders: %v", tauMarket, err)
+		return
+	}
+	onExchange := make(map[exchange.OrderID]exchange.Order, len(open))
+	for _, o := range open {
+		onExchange[o.ID] = o
+	}
+	var unknown []exchange.Order
+	var missing []exchange.OrderID
+	myOrders.RLock()
+	for _, o := range open {
+		if _, ok := myOrders.orders[o.ID]; !ok {
+			unknown = append(unknown, o)
+		}
+	}
+	for id := range myOrders.orders {
+		if _, ok := onExchange[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	myOrders.RUnlock()
+
+	for _, o := range unknown {
+		log.Warnf("Reconciler: cancelling order #%s %s %s at %s, not owned by any bot", o.ID, o.Side, o.Amount, o.Price)
+		if err := venue.CancelOrder(o.ID); err != nil {
+			log.Errorf("Reconciler: unable to cancel order #%s: %v", o.ID, err)
+		}
+	}
+	closed := make(map[exchange.OrderID]exchange.Order)
+	for _, id := range missing {
+		order, err := venue.GetOrder(id)
+		if err != nil {
+			log.Warnf("Reconciler: order #%s is not open and its state is unknown: %v", id, err)
+			continue
+		}
+		if order.State == exchange.OrderOpen {
+			continue //placed after the open orders were listed
+		}
+		closed[id] = order
+	}
+
+	myOrders.Lock()
+	defer myOrders.Unlock()
+	for _, o := range open {
+		mine, ok := myOrders.orders[o.ID]
+		if !ok {
+			continue
+		}
+		if !mine.Price.Equal(o.Price) || mine.Side != o.Side {
+			log.Warnf("Reconciler: order #%s is %s at %s on %s but %s at %s for the bots", o.ID, o.Side, o.Price, venue.Name(), mine.Side, mine.Price)
+		}
+		if !mine.Amount.Equal(o.Amount) {
+			log.Infof("Reconciler: order #%s has %s left of %s", o.ID, o.Amount, mine.Amount)
+			setOrder(o.ID, &myOrder{Bot: mine.Bot, Side: mine.Side, Price: mine.Price, Amount: o.Amount})
+		}
+	}
+	for id, order := range closed {
+		mine, ok := myOrders.orders[id]
+		if !ok {
+			continue //a bot dropped it meanwhile
+		}
+		log.Warnf("Reconciler: order #%s %s %s at %s is %s, filled %s", id, mine.Side, mine.Amount, mine.Price, order.State, order.Filled)
+		removeOrder(id)
+		recordClosed(closedOrder{
+			ID:       id,
+			Side:     mine.Side,
+			Price:    mine.Price,
+			Amount:   mine.Amount,
+			Filled:   order.Filled,
+			State:    order.State,
+			ClosedAt: time.Now(),
+		})
+	}
+}
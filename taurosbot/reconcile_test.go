@@ -0,0 +1,45 @@
+package taurosbot //trading-bot
+
+import (
+	"testing"
+
+	"git.vmo.mx/Tauros/tradingbot/exchange"
+)
+
+func TestReconcileOrders(t *testing.T) {
+	paper, _ := startPaper()
+	resetBots(unlockedVenue{paper, t})
+	filled, err := addOrder(0, "", dec("0.5"), "buy", dec("1900"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	partial, err := addOrder(1, "", dec("0.5"), "sell", dec("2100"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stray, err := paper.PlaceOrder("btc-mxn", "sell", dec("0.1"), dec("2500"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	paper.Fill(filled, dec("0.5"))
+	paper.Fill(partial, dec("0.1"))
+
+	reconcileOrders()
+
+	if o, _ := paper.GetOrder(stray); o.State != exchange.OrderCancelled {
+		t.Errorf("order #%s not owned by the bots is %s, want cancelled", stray, o.State)
+	}
+	tracked := trackedOrders()
+	if _, ok := tracked[filled]; ok || len(tracked) != 1 {
+		t.Errorf("tracked orders = %+v, want only #%s", tracked, partial)
+	}
+	if !tracked[partial].Amount.Equal(dec("0.4")) {
+		t.Errorf("partially filled order amount = %s, want 0.4", tracked[partial].Amount)
+	}
+	closedOrders.RLock()
+	closed := closedOrders.orders
+	closedOrders.RUnlock()
+	if len(closed) != 1 || closed[0].ID != filled || closed[0].State != exchange.OrderFilled || !closed[0].Filled.Equal(dec("0.5")) {
+		t.Errorf("closed orders = %+v, want #%s filled", closed, filled)
+	}
+}
@@ -0,0 +1,272 @@
+package taurosbot //trading-bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	pb "git.vmo.mx/Tauros/tradingbot/proto"
+	"github.com/shopspring/decimal"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+)
+
+// priceSource - where the bots get the reference price they quote around on Tauros,
+// prices are in the quote currency of the source market (usually USD)
+type priceSource interface {
+	Name() string
+	Ticker() (maxBid, minAsk decimal.Decimal, err error)
+	DepthPrice(side string, depth decimal.Decimal) (decimal.Decimal, error)
+}
+
+// priceSourceConf - reference price source in the bots configuration file
+type priceSourceConf struct {
+	Name    string          //used in the logs
+	Address string          //host:port of a gdax style grpc service, the gdax service if empty
+	Token   string          //auth token of the service at Address, none if empty
+	Market  string          //market in the source, the Coinbase market of the bots market if empty
+	Venue   string          //venue of the gdax service book: coinbase, kraken, binance or all
+	Mode    string          //depth price mode: "level" (default) or "vwap", the average price to fill the bot depth
+	Weight  decimal.Decimal //weight in the composite price, 1 if zero
+}
+
+// refPrice - reference price source of the bots, set up from the bots configuration in main
+var refPrice priceSource
+
+// sourceTimeout - how long to wait for a price source before leaving it out
+var sourceTimeout = 2 * time.Second
+
+// grpcSource - price source backed by the gdax grpc service or any service with the same api
+type grpcSource struct {
+	name   string
+	market string
+	venue  string
+	mode   string
+	ticker pb.TickerServiceClient
+	spread pb.SpreadPriceServiceClient
+}
+
+func newGrpcSource(name, market, venue, mode string, conn *grpc.ClientConn) *grpcSource {
+	return &grpcSource{
+		name:   name,
+		market: market,
+		venue:  venue,
+		mode:   mode,
+		ticker: pb.NewTickerServiceClient(conn),
+		spread: pb.NewSpreadPriceServiceClient(conn),
+	}
+}
+
+func (s *grpcSource) Name() string {
+	return s.name
+}
+
+func (s *grpcSource) Ticker() (maxBid, minAsk decimal.Decimal, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), sourceTimeout)
+	defer cancel()
+	res, err := s.ticker.GetTicker(ctx, &pb.TickerRequest{Market: s.market, Venue: s.venue})
+	if err != nil {
+		return maxBid, minAsk, fmt.Errorf("%s ticker-> %v", s.name, err)
+	}
+	if maxBid, err = decimal.NewFromString(res.MaxBid); err != nil {
+		return maxBid, minAsk, fmt.Errorf("%s bad ticker MaxBid %s: %v", s.name, res.MaxBid, err)
+	}
+	if minAsk, err = decimal.NewFromString(res.MinAsk); err != nil {
+		return maxBid, minAsk, fmt.Errorf("%s bad ticker MinAsk %s: %v", s.name, res.MinAsk, err)
+	}
+	return maxBid, minAsk, nil
+}
+
+func (s *grpcSource) DepthPrice(side string, depth decimal.Decimal) (decimal.Decimal, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), sourceTimeout)
+	defer cancel()
+	res, err := s.spread.GetSpreadPrice(ctx, &pb.SpreadPriceRequest{
+		Market: s.market,
+		Side:   side,
+		Depth:  depth.String(),
+		Venue:  s.venue,
+		Mode:   s.mode,
+	})
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("%s depth price-> %v", s.name, err)
+	}
+	price, err := decimal.NewFromString(res.Price)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("%s bad depth price %s: %v", s.name, res.Price, err)
+	}
+	return price, nil
+}
+
+// compositeSource - weighted average of several sources. Sources that fail or whose price is more
+// than maxDeviation away from the median of all sources are left out, so an outage or a manipulated
+// book in one venue does not move the quotes
+type compositeSource struct {
+	sources      []priceSource
+	weights      []decimal.Decimal
+	maxDeviation decimal.Decimal //fraction of the median, zero to keep every source
+	minSources   int             //fewer good sources than this is an error
+}
+
+// sourcePrice - price given by one of the sources of a composite
+type sourcePrice struct {
+	source int
+	price  decimal.Decimal
+	bid    decimal.Decimal
+	ask    decimal.Decimal
+}
+
+func (c *compositeSource) Name() string {
+	names := make([]string, len(c.sources))
+	for i, s := range c.sources {
+		names[i] = s.Name()
+	}
+	return "composite(" + strings.Join(names, ",") + ")"
+}
+
+func (c *compositeSource) Ticker() (maxBid, minAsk decimal.Decimal, err error) {
+	prices := c.query(func(s priceSource) (sourcePrice, error) {
+		bid, ask, err := s.Ticker()
+		if err == nil && bid.GreaterThanOrEqual(ask) {
+			err = fmt.Errorf("%s crossed ticker bid %s ask %s", s.Name(), bid, ask)
+		}
+		return sourcePrice{price: decimal.Avg(bid, ask), bid: bid, ask: ask}, err
+	})
+	good, err := c.filter(prices)
+	if err != nil {
+		return maxBid, minAsk, fmt.Errorf("Ticker-> %v", err)
+	}
+	maxBid = c.average(good, func(p sourcePrice) decimal.Decimal { return p.bid })
+	minAsk = c.average(good, func(p sourcePrice) decimal.Decimal { return p.ask })
+	return maxBid, minAsk, nil
+}
+
+func (c *compositeSource) DepthPrice(side string, depth decimal.Decimal) (decimal.Decimal, error) {
+	prices := c.query(func(s priceSource) (sourcePrice, error) {
+		price, err := s.DepthPrice(side, depth)
+		return sourcePrice{price: price}, err
+	})
+	good, err := c.filter(prices)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("DepthPrice-> %v", err)
+	}
+	return c.average(good, func(p sourcePrice) decimal.Decimal { return p.price }), nil
+}
+
+// query asks all sources at the same time, the ones that fail are logged and left out
+func (c *compositeSource) query(get func(priceSource) (sourcePrice, error)) []sourcePrice {
+	var mux sync.Mutex
+	var wg sync.WaitGroup
+	var prices []sourcePrice
+	for i, s := range c.sources {
+		wg.Add(1)
+		go func(i int, s priceSource) {
+			defer wg.Done()
+			p, err := get(s)
+			if err == nil && p.price.Sign() <= 0 {
+				err = fmt.Errorf("%s price %s is not positive", s.Name(), p.price)
+			}
+			if err != nil {
+				log.Warnf("Leaving out price source %s: %v", s.Name(), err)
+				return
+			}
+			p.source = i
+			mux.Lock()
+			prices = append(prices, p)
+			mux.Unlock()
+		}(i, s)
+	}
+	wg.Wait()
+	return prices
+}
+
+// filter leaves out the prices too far from the median
+func (c *compositeSource) filter(prices []sourcePrice) ([]sourcePrice, error) {
+	if len(prices) == 0 {
+		return nil, errors.New("no price source available")
+	}
+	median := medianPrice(prices)
+	var good []sourcePrice
+	for _, p := range prices {
+		if c.maxDeviation.Sign() > 0 {
+			if deviation := p.price.Sub(median).Abs().Div(median); deviation.GreaterThan(c.maxDeviation) {
+				log.Warnf("Leaving out price source %s: price %s is %s away from the median %s", c.sources[p.source].Name(), p.price, deviation, median)
+				continue
+			}
+		}
+		good = append(good, p)
+	}
+	if len(good) < c.minSources {
+		return nil, fmt.Errorf("only %d of %d price sources agree, %d needed", len(good), len(c.sources), c.minSources)
+	}
+	return good, nil
+}
+
+func (c *compositeSource) average(prices []sourcePrice, value func(sourcePrice) decimal.Decimal) decimal.Decimal {
+	var sum, weights decimal.Decimal
+	for _, p := range prices {
+		w := c.weights[p.source]
+		sum = sum.Add(value(p).Mul(w))
+		weights = weights.Add(w)
+	}
+	return sum.Div(weights)
+}
+
+func medianPrice(prices []sourcePrice) decimal.Decimal {
+	sorted := make([]decimal.Decimal, len(prices))
+	for i, p := range prices {
+		sorted[i] = p.price
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].LessThan(sorted[j]) })
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return decimal.Avg(sorted[n/2-1], sorted[n/2])
+}
+
+// setupPriceSources creates the reference price source from the bots configuration, the gdax
+// service alone if no sources are configured
+func setupPriceSources(gdaxConn *grpc.ClientConn) (conns []*grpc.ClientConn) {
+	if len(bots.PriceSources) == 0 {
+		refPrice = newGrpcSource("gdax", gdaxMarket, "", "", gdaxConn)
+		return nil
+	}
+	c := &compositeSource{maxDeviation: bots.MaxSourceDeviation, minSources: bots.MinSources}
+	if c.minSources < 1 {
+		c.minSources = 1
+	}
+	for _, conf := range bots.PriceSources {
+		conn := gdaxConn
+		if conf.Address != "" {
+			var err error
+			log.Infof("Connecting to price source %s at %s", conf.Name, conf.Address)
+			if conn, err = dialService(conf.Address, conf.Token); err != nil {
+				log.Fatalf("Unable to connect to price source %s at %s: %v", conf.Name, conf.Address, err)
+			}
+			conns = append(conns, conn)
+		}
+		if conf.Mode != "" && conf.Mode != "level" && conf.Mode != "vwap" {
+			log.Fatalf("Price source %s mode must be level or vwap", conf.Name)
+		}
+		market := conf.Market
+		if market == "" {
+			market = gdaxMarket
+		}
+		weight := conf.Weight
+		if weight.IsZero() {
+			weight = decimal.New(1, 0)
+		}
+		if weight.Sign() < 0 {
+			log.Fatalf("Price source %s weight cannot be negative", conf.Name)
+		}
+		c.sources = append(c.sources, newGrpcSource(conf.Name, market, conf.Venue, conf.Mode, conn))
+		c.weights = append(c.weights, weight)
+	}
+	log.Infof("Reference price from %s, max deviation %s, min sources %d", c.Name(), c.maxDeviation, c.minSources)
+	refPrice = c
+	return conns
+}
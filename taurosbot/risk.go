@@ -0,0 +1,248 @@
+package taurosbot //trading-bot
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"git.vmo.mx/Tauros/tradingbot/exchange"
+	"github.com/shopspring/decimal"
+	log "github.com/sirupsen/logrus"
+)
+
+// riskLimits - limits checked before every new order and on every fill, zero disables a limit.
+// Breaching any of them cancels all the bots orders and halts quoting until resumed with
+// SIGUSR1 or POST /resume in the control endpoint
+type riskLimits struct {
+	MaxOpenNotional       decimal.Decimal //max value of the open orders of each side, in the quote coin
+	MaxInventoryDeviation decimal.Decimal //max difference between the base coin share of the balances and the Inventory Target
+	MaxDailyLoss          decimal.Decimal //max loss of the fills of the day (UTC), in the quote coin
+	MaxOrdersPerMinute    int
+	MaxQuoteDeviation     decimal.Decimal //max difference of an order price from the reference mid price, as a fraction of it
+}
+
+// risk state of the bots
+var risk struct {
+	sync.Mutex
+	halted  bool
+	exiting bool //the process is exiting, unlike halted it is not kept in the state file
+	reason  string
+	placed  []time.Time //orders placed in the last minute
+	day     time.Time   //day of the fills in cash and base
+	cash    decimal.Decimal
+	base    decimal.Decimal
+}
+
+// riskHalted - true if quoting is halted, and why
+func riskHalted() (bool, string) {
+	risk.Lock()
+	defer risk.Unlock()
+	return risk.halted || risk.exiting, risk.reason
+}
+
+// dailyPnL - profit of the fills of the day, the base coin bought or sold is valued at mark price
+func dailyPnL(mark decimal.Decimal) decimal.Decimal {
+	risk.Lock()
+	defer risk.Unlock()
+	if !risk.day.Equal(today()) {
+		return decimal.Zero
+	}
+	return risk.cash.Add(risk.base.Mul(mark))
+}
+
+func today() time.Time {
+	return time.Now().UTC().Truncate(24 * time.Hour)
+}
+
+// markPrice - reference mid price in the quote coin of the market
+func markPrice() decimal.Decimal {
+	marketData.RLock()
+	defer marketData.RUnlock()
+	return marketData.mid.Mul(marketData.currentExchangeRate)
+}
+
+// checkOrderRisk returns an error if placing the order breaches a limit. orders are the bots open
+// orders without the one being replaced, the caller must hold the myOrders lock
+func checkOrderRisk(side string, price, amount decimal.Decimal, orders map[exchange.OrderID]*myOrder) error {
+	limits := bots.Risk
+	if halted, reason := riskHalted(); halted {
+		return fmt.Errorf("quoting is halted: %s", reason)
+	}
+	if limits == nil {
+		return nil
+	}
+	if limits.MaxOrdersPerMinute > 0 {
+		risk.Lock()
+		var recent []time.Time
+		for _, t := range risk.placed {
+			if time.Since(t) < time.Minute {
+				recent = append(recent, t)
+			}
+		}
+		risk.placed = recent
+		n := len(recent)
+		risk.Unlock()
+		if n >= limits.MaxOrdersPerMinute {
+			return fmt.Errorf("%d orders placed in the last minute, max %d", n, limits.MaxOrdersPerMinute)
+		}
+	}
+	if limits.MaxOpenNotional.Sign() > 0 {
+		notional := price.Mul(amount)
+		for _, o := range orders {
+			if o.Side == side {
+				notional = notional.Add(o.Price.Mul(o.Amount))
+			}
+		}
+		if notional.GreaterThan(limits.MaxOpenNotional) {
+			return fmt.Errorf("open %s notional %s is over the max %s", side, notional, limits.MaxOpenNotional)
+		}
+	}
+	mark := markPrice()
+	if limits.MaxQuoteDeviation.Sign() > 0 {
+		if mark.Sign() <= 0 {
+			return fmt.Errorf("no reference price to check the %s price %s", side, price)
+		}
+		if deviation := price.Div(mark).Sub(decimal.New(1, 0)).Abs(); deviation.GreaterThan(limits.MaxQuoteDeviation) {
+			return fmt.Errorf("%s price %s is %s away from the reference price %s, max %s", side, price, deviation, mark, limits.MaxQuoteDeviation)
+		}
+	}
+	if limits.MaxInventoryDeviation.Sign() > 0 {
+		marketData.RLock()
+		deviation := marketData.inventory.Sub(inventoryConfig().Target).Abs()
+		marketData.RUnlock()
+		if deviation.GreaterThan(limits.MaxInventoryDeviation) {
+			return fmt.Errorf("inventory is %s away from the target, max %s", deviation, limits.MaxInventoryDeviation)
+		}
+	}
+	return checkDailyLoss(mark)
+}
+
+func checkDailyLoss(mark decimal.Decimal) error {
+	if bots.Risk == nil || bots.Risk.MaxDailyLoss.Sign() <= 0 {
+		return nil
+	}
+	if pnl := dailyPnL(mark); pnl.Neg().GreaterThan(bots.Risk.MaxDailyLoss) {
+		return fmt.Errorf("daily loss %s is over the max %s", pnl.Neg(), bots.Risk.MaxDailyLoss)
+	}
+	return nil
+}
+
+// orderPlaced records an order for the orders per minute limit
+func orderPlaced() {
+	risk.Lock()
+	risk.placed = append(risk.placed, time.Now())
+	risk.Unlock()
+}
+
+// addFill records a fill in the daily pnl and halts the bots if the loss is over the limit
+func addFill(f exchange.Fill) {
+	risk.Lock()
+	journal(stateRecord{Op: "fill", Fill: &f})
+	applyFill(f)
+	risk.Unlock()
+	if err := checkDailyLoss(markPrice()); err != nil {
+		haltBots(err.Error())
+	}
+}
+
+// applyFill adds a fill to the daily pnl, fills of a previous day are left out. The caller holds the risk lock
+func applyFill(f exchange.Fill) {
+	day := today()
+	if !f.Time.IsZero() {
+		day = f.Time.UTC().Truncate(24 * time.Hour)
+	}
+	if day.Before(risk.day) {
+		return
+	}
+	if !risk.day.Equal(day) {
+		risk.day, risk.cash, risk.base = day, decimal.Zero, decimal.Zero
+	}
+	value := f.Amount.Mul(f.Price)
+	if f.Side == "buy" {
+		risk.cash = risk.cash.Sub(value)
+		risk.base = risk.base.Add(f.Amount)
+	} else {
+		risk.cash = risk.cash.Add(value)
+		risk.base = risk.base.Sub(f.Amount)
+	}
+	if strings.EqualFold(f.FeeCoin, buySide) {
+		risk.base = risk.base.Sub(f.Fee)
+	} else {
+		risk.cash = risk.cash.Sub(f.Fee)
+	}
+}
+
+// haltBots stops quoting and cancels all the bots orders, if they are already halted it cancels the
+// orders left by the previous halt. Must not be called holding the myOrders lock
+func haltBots(reason string) {
+	if haltQuoting(reason) {
+		log.Errorf("Risk limit breached, halting the bots and cancelling their orders: %s", reason)
+	} else {
+		log.Warnf("The bots are already halted, cancelling their orders left: %s", reason)
+	}
+	cancelBotOrders()
+}
+
+// haltQuoting stops quoting at once, true if the bots were not halted yet. It does not cancel the
+// orders so it can be called holding the myOrders lock
+func haltQuoting(reason string) bool {
+	risk.Lock()
+	defer risk.Unlock()
+	if risk.halted {
+		return false
+	}
+	journal(stateRecord{Op: "halt", Reason: reason})
+	risk.halted = true
+	risk.reason = reason
+	return true
+}
+
+// cancelBotOrders cancels every order in myOrders, without holding the myOrders lock while cancelling
+func cancelBotOrders() {
+	myOrders.RLock()
+	ids := make([]exchange.OrderID, 0, len(myOrders.orders))
+	for id := range myOrders.orders {
+		ids = append(ids, id)
+	}
+	myOrders.RUnlock()
+	cancelOrders(ids)
+}
+
+// cancelOrders cancels the orders ids without holding the myOrders lock and then forgets the
+// cancelled ones, the ones it cannot cancel are kept to try again
+func cancelOrders(ids []exchange.OrderID) {
+	var cancelled []exchange.OrderID
+	for _, id := range ids {
+		if err := venue.CancelOrder(id); err != nil {
+			log.Errorf("Unable to cancel order #%s: %v", id, err)
+			continue
+		}
+		cancelled = append(cancelled, id)
+	}
+	if len(cancelled) == 0 {
+		return
+	}
+	myOrders.Lock()
+	defer myOrders.Unlock()
+	for _, id := range cancelled {
+		if myOrders.orders[id] != nil {
+			removeOrder(id)
+		}
+	}
+}
+
+// resumeBots lets the bots quote again after a halt
+func resumeBots() {
+	risk.Lock()
+	wasHalted, reason := risk.halted, risk.reason
+	if wasHalted {
+		journal(stateRecord{Op: "resume"})
+	}
+	risk.halted = false
+	risk.reason = ""
+	risk.Unlock()
+	if wasHalted {
+		log.Warnf("Resuming the bots, they were halted because: %s", reason)
+	}
+}
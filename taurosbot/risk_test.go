@@ -0,0 +1,94 @@
+package taurosbot //trading-bot
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"git.vmo.mx/Tauros/tradingbot/exchange"
+)
+
+// unlockedVenue - exchange that fails the test if an order is cancelled or asked for holding the myOrders lock
+type unlockedVenue struct {
+	*exchange.Fake
+	t *testing.T
+}
+
+func (v unlockedVenue) checkUnlocked(what string, id exchange.OrderID) {
+	free := make(chan bool)
+	go func() {
+		myOrders.Lock()
+		myOrders.Unlock()
+		close(free)
+	}()
+	select {
+	case <-free:
+	case <-time.After(time.Second):
+		v.t.Errorf("%s order #%s holding the myOrders lock", what, id)
+	}
+}
+
+func (v unlockedVenue) CancelOrder(id exchange.OrderID) error {
+	v.checkUnlocked("cancelled", id)
+	return v.Fake.CancelOrder(id)
+}
+
+func (v unlockedVenue) GetOrder(id exchange.OrderID) (exchange.Order, error) {
+	v.checkUnlocked("asked for", id)
+	return v.Fake.GetOrder(id)
+}
+
+func TestRiskBreachHaltsAtOnce(t *testing.T) {
+	paper, _ := startPaper()
+	bots.Risk = &riskLimits{MaxOpenNotional: dec("3000")}
+
+	buy, err := addOrder(0, "", dec("1"), "buy", dec("2000"))
+	if err != nil || buy == "" {
+		t.Fatalf("addOrder = %q, %v", buy, err)
+	}
+	if id, err := addOrder(1, "", dec("1"), "buy", dec("1990")); id != "" || err != nil {
+		t.Fatalf("addOrder over the notional = %q, %v, want no order", id, err)
+	}
+	if halted, _ := riskHalted(); !halted {
+		t.Fatal("the bots are not halted when addOrder returns")
+	}
+	if id, _ := addOrder(2, "", dec("0.1"), "sell", dec("2100")); id != "" {
+		t.Errorf("order #%s placed after the halt", id)
+	}
+	waitFor(t, "the bots orders cancelled", func() bool {
+		open, _ := paper.OpenOrders("btc-mxn")
+		return len(open) == 0 && len(trackedOrders()) == 0
+	})
+}
+
+func TestRepeatedHaltCancelsLeftovers(t *testing.T) {
+	paper, _ := startPaper()
+	resetBots(unlockedVenue{paper, t})
+	if _, err := addOrder(0, "", dec("0.5"), "buy", dec("1900")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := addOrder(1, "", dec("0.5"), "sell", dec("2100")); err != nil {
+		t.Fatal(err)
+	}
+
+	paper.Err = errors.New("exchange down")
+	haltBots("first halt")
+	if halted, reason := riskHalted(); !halted || reason != "first halt" {
+		t.Fatalf("riskHalted = %t, %q", halted, reason)
+	}
+	if n := len(trackedOrders()); n != 2 {
+		t.Fatalf("%d orders tracked after failing to cancel them, want 2", n)
+	}
+
+	paper.Err = nil
+	haltBots("second halt")
+	if n := len(trackedOrders()); n != 0 {
+		t.Errorf("%d orders left after halting again", n)
+	}
+	if open, _ := paper.OpenOrders("btc-mxn"); len(open) != 0 {
+		t.Errorf("open orders after halting again = %+v", open)
+	}
+	if _, reason := riskHalted(); reason != "first halt" {
+		t.Errorf("halt reason = %q, want the first one", reason)
+	}
+}
@@ -0,0 +1,165 @@
+package taurosbot //trading-bot
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"git.vmo.mx/Tauros/tradingbot/exchange"
+	log "github.com/sirupsen/logrus"
+)
+
+// exitRequests - reasons to shut down from inside the process, read by main
+var exitRequests = make(chan string, 1)
+
+func shutdownTimeout() time.Duration {
+	if bots.ShutdownTimeout > 0 {
+		return time.Duration(bots.ShutdownTimeout) * time.Second
+	}
+	return 30 * time.Second
+}
+
+// requestExit stops quoting at once and asks main to shut down
+func requestExit(reason string) {
+	risk.Lock()
+	risk.exiting, risk.reason = true, reason
+	risk.Unlock()
+	select {
+	case exitRequests <- reason:
+	default: //already shutting down
+	}
+}
+
+// shutdown stops the bots and the background goroutines with stop, cancels every order the process
+// owns until none is open in the exchange and saves the state, all within ShutdownTimeout and at most
+// saveTimeout more to save the state. It returns the orders that could not be cancelled, and an error
+// if the orders could not even be listed
+func shutdown(stop context.CancelFunc) ([]exchange.OrderID, error) {
+	deadline := time.Now().Add(shutdownTimeout())
+	risk.Lock()
+	risk.exiting = true
+	risk.Unlock()
+	//listed before stopping the bots, a bot stuck holding the myOrders lock cannot keep them from it later
+	listed := make(chan map[exchange.OrderID]myOrder, 1)
+	go func() {
+		owned := make(map[exchange.OrderID]myOrder)
+		myOrders.RLock()
+		for id, o := range myOrders.orders {
+			owned[id] = *o
+		}
+		myOrders.RUnlock()
+		listed <- owned
+	}()
+	var owned map[exchange.OrderID]myOrder
+	select {
+	case owned = <-listed:
+	case <-time.After(time.Until(deadline)):
+	}
+	stop()
+	stopped := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		log.Info("All the bots stopped")
+	case <-time.After(time.Until(deadline)):
+		log.Errorf("The bots did not stop in %s, cancelling their orders anyway", shutdownTimeout())
+	}
+	if owned == nil {
+		return nil, fmt.Errorf("shutdown-> unable to list the bots orders in %s, the myOrders lock is held", shutdownTimeout())
+	}
+	left := cancelOwnedOrders(owned, deadline)
+	saveState(deadline)
+	return left, nil
+}
+
+// cancelOwnedOrders cancels the orders in myOrders and checks the open orders of the market until
+// none of the owned ones is open or deadline passes, returning the ones that may still be open.
+// The cancels run in their own goroutine so neither a slow exchange nor a bot stuck holding the
+// myOrders lock keep it past deadline
+func cancelOwnedOrders(owned map[exchange.OrderID]myOrder, deadline time.Time) []exchange.OrderID {
+	var progress struct {
+		sync.Mutex
+		left []exchange.OrderID
+	}
+	for id := range owned {
+		progress.left = append(progress.left, id)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			cancelBotOrders()
+			open, err := venue.OpenOrders(tauMarket)
+			if err != nil {
+				log.Errorf("Unable to check %s open orders: %v", tauMarket, err)
+			} else {
+				stillOpen := make(map[exchange.OrderID]bool)
+				var left []exchange.OrderID
+				for _, o := range open {
+					if _, ok := owned[o.ID]; ok {
+						stillOpen[o.ID] = true
+						left = append(left, o.ID)
+					}
+				}
+				progress.Lock()
+				progress.left = left
+				progress.Unlock()
+				//orders cancelled but still open are tried again, the ones that are not open are done
+				myOrders.Lock()
+				for id := range owned {
+					if mine := myOrders.orders[id]; stillOpen[id] && mine == nil {
+						o := owned[id]
+						setOrder(id, &o)
+					} else if !stillOpen[id] && mine != nil {
+						removeOrder(id)
+					}
+				}
+				myOrders.Unlock()
+				if len(stillOpen) == 0 {
+					log.Infof("All the %d orders of the bots are closed", len(owned))
+					return
+				}
+			}
+			if time.Now().Add(time.Second).After(deadline) {
+				return
+			}
+			time.Sleep(time.Second)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Until(deadline)):
+		log.Errorf("The bots orders are not cancelled after %s", shutdownTimeout())
+	}
+	progress.Lock()
+	defer progress.Unlock()
+	return append([]exchange.OrderID(nil), progress.left...)
+}
+
+// saveTimeout - least time given to save the state when the shutdown deadline is already past
+var saveTimeout = time.Second
+
+// saveState writes the state snapshot if it can before deadline or within saveTimeout, otherwise
+// the state is restored from the journal
+func saveState(deadline time.Time) {
+	saved := make(chan error, 1)
+	go func() {
+		saved <- writeSnapshot()
+	}()
+	wait := time.Until(deadline)
+	if wait < saveTimeout {
+		wait = saveTimeout
+	}
+	select {
+	case err := <-saved:
+		if err != nil {
+			log.Errorf("Unable to save the bots state: %v", err)
+		}
+	case <-time.After(wait):
+		log.Errorf("Unable to save the bots state in %s, it is restored from its journal", wait)
+	}
+}
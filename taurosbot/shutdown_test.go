@@ -0,0 +1,107 @@
+package taurosbot //trading-bot
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"git.vmo.mx/Tauros/tradingbot/exchange"
+)
+
+// slowVenue - exchange whose cancels wait until hold is closed and that tells on checked every time
+// the open orders are asked for
+type slowVenue struct {
+	*exchange.Fake
+	hold    chan struct{}
+	checked chan struct{}
+}
+
+func newSlowVenue(paper *exchange.Fake) slowVenue {
+	return slowVenue{paper, make(chan struct{}), make(chan struct{}, 100)}
+}
+
+func (v slowVenue) CancelOrder(id exchange.OrderID) error {
+	<-v.hold
+	return v.Fake.CancelOrder(id)
+}
+
+func (v slowVenue) OpenOrders(market string) ([]exchange.Order, error) {
+	defer func() { v.checked <- struct{}{} }()
+	return v.Fake.OpenOrders(market)
+}
+
+// shutdownInTime runs shutdown and fails the test if it takes much longer than ShutdownTimeout
+func shutdownInTime(t *testing.T, stop context.CancelFunc) ([]exchange.OrderID, error) {
+	type result struct {
+		left []exchange.OrderID
+		err  error
+	}
+	done := make(chan result, 1)
+	start := time.Now()
+	go func() {
+		left, err := shutdown(stop)
+		done <- result{left, err}
+	}()
+	select {
+	case r := <-done:
+		if d := time.Since(start); d > shutdownTimeout()+500*time.Millisecond {
+			t.Errorf("shutdown took %s, past its %s deadline", d, shutdownTimeout())
+		}
+		return r.left, r.err
+	case <-time.After(5 * time.Second):
+		t.Fatalf("shutdown did not return in 5s with a %s deadline", shutdownTimeout())
+	}
+	return nil, nil
+}
+
+func TestShutdownDeadlineWithSlowCancels(t *testing.T) {
+	paper, _ := startPaper()
+	v := newSlowVenue(paper)
+	resetBots(v)
+	defer func(s int) { bots.ShutdownTimeout = s }(bots.ShutdownTimeout)
+	bots.ShutdownTimeout = 1
+	id, err := addOrder(0, "", dec("0.5"), "buy", dec("1900"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	left, err := shutdownInTime(t, func() {})
+	if err != nil || len(left) != 1 || left[0] != id {
+		t.Errorf("shutdown = %v, %v, want #%s left", left, err, id)
+	}
+	close(v.hold)
+	<-v.checked //the cancels go on in the background until they are checked
+}
+
+func TestShutdownDeadlineWithBotHoldingLock(t *testing.T) {
+	paper, _ := startPaper()
+	v := newSlowVenue(paper)
+	close(v.hold)
+	resetBots(v)
+	defer func(s int) { bots.ShutdownTimeout = s }(bots.ShutdownTimeout)
+	bots.ShutdownTimeout = 1
+	id, err := addOrder(0, "", dec("0.5"), "buy", dec("1900"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//a bot that gets stuck holding the myOrders lock when it is stopped
+	ctx, stop := context.WithCancel(context.Background())
+	release := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		<-ctx.Done()
+		myOrders.Lock()
+		<-release
+		myOrders.Unlock()
+	}()
+
+	left, err := shutdownInTime(t, stop)
+	if err != nil || len(left) != 1 || left[0] != id {
+		t.Errorf("shutdown = %v, %v, want #%s left", left, err, id)
+	}
+	close(release)
+	<-v.checked
+	wg.Wait()
+}
@@ -0,0 +1,312 @@
+package taurosbot //trading-bot
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"git.vmo.mx/Tauros/tradingbot/exchange"
+	"github.com/shopspring/decimal"
+	log "github.com/sirupsen/logrus"
+)
+
+// stateSnapshot - bots state kept in StateFile: the orders and which bot owns each one, the fills
+// of the day, the last closed orders, the halt and the last inventory
+type stateSnapshot struct {
+	Orders     map[exchange.OrderID]*myOrder `json:"orders"`
+	Day        time.Time                     `json:"day"`
+	Cash       decimal.Decimal               `json:"cash"`
+	Base       decimal.Decimal               `json:"base"`
+	Halted     bool                          `json:"halted"`
+	HaltReason string                        `json:"halt_reason,omitempty"`
+	Closed     []closedOrder                 `json:"closed_orders"`
+	Inventory  decimal.Decimal               `json:"inventory"`
+	Time       time.Time                     `json:"time"`
+}
+
+// stateRecord - a change of the bots state, appended to the journal before it is applied
+type stateRecord struct {
+	Op     string           `json:"op"` //order, remove, fill, closed, halt or resume
+	ID     exchange.OrderID `json:"id,omitempty"`
+	Order  *myOrder         `json:"order,omitempty"`
+	Fill   *exchange.Fill   `json:"fill,omitempty"`
+	Closed *closedOrder     `json:"closed,omitempty"`
+	Reason string           `json:"reason,omitempty"`
+}
+
+// stateStore - StateFile holds the last snapshot and StateFile.wal the records since then, every
+// write is synced to disk. nil if the bots have no StateFile
+type stateStore struct {
+	sync.Mutex
+	path string
+	wal  *os.File
+}
+
+var state *stateStore
+
+// openState loads the snapshot in path, replays its journal on top and starts a new snapshot
+func openState(path string) error {
+	s := &stateStore{path: path}
+	snapshot, err := readSnapshot(path)
+	if err != nil {
+		return fmt.Errorf("openState-> %v", err)
+	}
+	restoreSnapshot(snapshot)
+	n, err := replayJournal(path + ".wal")
+	if err != nil {
+		return fmt.Errorf("openState-> %v", err)
+	}
+	s.wal, err = os.OpenFile(path+".wal", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("openState-> %v", err)
+	}
+	state = s
+	myOrders.RLock()
+	log.Infof("Restored %d orders from %s and %d journal records", len(myOrders.orders), path, n)
+	myOrders.RUnlock()
+	return writeSnapshot()
+}
+
+func readSnapshot(path string) (*stateSnapshot, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	snapshot := new(stateSnapshot)
+	if err := json.Unmarshal(data, snapshot); err != nil {
+		return nil, fmt.Errorf("bad snapshot %s: %v", path, err)
+	}
+	return snapshot, nil
+}
+
+func restoreSnapshot(s *stateSnapshot) {
+	if s == nil {
+		return
+	}
+	myOrders.Lock()
+	for id, o := range s.Orders {
+		myOrders.orders[id] = o
+	}
+	myOrders.Unlock()
+	risk.Lock()
+	risk.day, risk.cash, risk.base = s.Day, s.Cash, s.Base
+	risk.halted, risk.reason = s.Halted, s.HaltReason
+	risk.Unlock()
+	closedOrders.Lock()
+	closedOrders.orders = s.Closed
+	closedOrders.Unlock()
+	marketData.Lock()
+	marketData.inventory = s.Inventory
+	marketData.Unlock()
+}
+
+// replayJournal applies the records in path, a torn last record from a crash is left out
+func replayJournal(path string) (int, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	n := 0
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var r stateRecord
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			log.Warnf("Skipping the rest of %s, bad record %d: %v", path, n+1, err)
+			break
+		}
+		applyRecord(r)
+		n++
+	}
+	return n, scanner.Err()
+}
+
+func applyRecord(r stateRecord) {
+	switch r.Op {
+	case "order":
+		myOrders.Lock()
+		myOrders.orders[r.ID] = r.Order
+		myOrders.Unlock()
+	case "remove":
+		myOrders.Lock()
+		delete(myOrders.orders, r.ID)
+		myOrders.Unlock()
+	case "fill":
+		risk.Lock()
+		applyFill(*r.Fill)
+		risk.Unlock()
+	case "closed":
+		closedOrders.Lock()
+		appendClosed(*r.Closed)
+		closedOrders.Unlock()
+	case "halt":
+		risk.Lock()
+		risk.halted, risk.reason = true, r.Reason
+		risk.Unlock()
+	case "resume":
+		risk.Lock()
+		risk.halted, risk.reason = false, ""
+		risk.Unlock()
+	default:
+		log.Warnf("Unknown state record %q", r.Op)
+	}
+}
+
+// journal appends r to the journal and syncs it before the change is applied. The locks are taken
+// in the order myOrders, risk, closedOrders and then the store
+func journal(r stateRecord) {
+	if state == nil {
+		return
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		log.Errorf("Unable to journal %s record: %v", r.Op, err)
+		return
+	}
+	state.Lock()
+	defer state.Unlock()
+	if _, err := state.wal.Write(append(data, '\n')); err != nil {
+		log.Errorf("Unable to journal %s record: %v", r.Op, err)
+		return
+	}
+	if err := state.wal.Sync(); err != nil {
+		log.Errorf("Unable to sync the journal: %v", err)
+	}
+}
+
+// writeSnapshot saves the bots state in StateFile and empties the journal
+func writeSnapshot() error {
+	if state == nil {
+		return nil
+	}
+	marketData.RLock()
+	inventory := marketData.inventory
+	marketData.RUnlock()
+	myOrders.RLock()
+	defer myOrders.RUnlock()
+	risk.Lock()
+	defer risk.Unlock()
+	closedOrders.RLock()
+	defer closedOrders.RUnlock()
+	state.Lock()
+	defer state.Unlock()
+	snapshot := stateSnapshot{
+		Orders:    myOrders.orders,
+		Day:       risk.day,
+		Cash:      risk.cash,
+		Base:      risk.base,
+		Halted:    risk.halted,
+		Closed:    closedOrders.orders,
+		Inventory: inventory,
+		Time:      time.Now(),
+	}
+	if risk.halted {
+		snapshot.HaltReason = risk.reason
+	}
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return fmt.Errorf("writeSnapshot-> %v", err)
+	}
+	if err := writeFileSync(state.path, data); err != nil {
+		return fmt.Errorf("writeSnapshot-> %v", err)
+	}
+	if err := state.wal.Truncate(0); err != nil {
+		return fmt.Errorf("writeSnapshot-> %v", err)
+	}
+	if err := state.wal.Sync(); err != nil {
+		return fmt.Errorf("writeSnapshot-> %v", err)
+	}
+	return nil
+}
+
+// writeFileSync replaces path with data through a synced temporary file, so a crash leaves either
+// the old or the new file
+func writeFileSync(path string, data []byte) error {
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	dir, err := os.Open(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
+
+// runSnapshots compacts the journal into a new snapshot every interval until ctx is done
+func runSnapshots(ctx context.Context, interval time.Duration) {
+	defer wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := writeSnapshot(); err != nil {
+				log.Errorf("Unable to save the bots state: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// setOrder journals and keeps track of a bot order, the caller holds the myOrders lock
+func setOrder(id exchange.OrderID, o *myOrder) {
+	journal(stateRecord{Op: "order", ID: id, Order: o})
+	myOrders.orders[id] = o
+}
+
+// removeOrder journals and forgets a bot order, the caller holds the myOrders lock
+func removeOrder(id exchange.OrderID) {
+	journal(stateRecord{Op: "remove", ID: id})
+	delete(myOrders.orders, id)
+}
+
+// adoptOrders returns the restored order of each bot, orders no bot can take over are cancelled
+// without holding the myOrders lock
+func adoptOrders(bs []bot) map[int]exchange.OrderID {
+	owned := make(map[int]exchange.OrderID)
+	var orphans []exchange.OrderID
+	myOrders.RLock()
+	for id, o := range myOrders.orders {
+		if _, taken := owned[o.Bot]; !taken && o.Bot >= 0 && o.Bot < len(bs) && bs[o.Bot].Side == o.Side {
+			log.Infof("Bot %d takes over its order #%s %s %s at %s", o.Bot, id, o.Side, o.Amount, o.Price)
+			owned[o.Bot] = id
+			continue
+		}
+		log.Warnf("Cancelling order #%s %s %s at %s, no bot %d to take it over", id, o.Side, o.Amount, o.Price, o.Bot)
+		orphans = append(orphans, id)
+	}
+	myOrders.RUnlock()
+	cancelOrders(orphans)
+	return owned
+}
@@ -0,0 +1,31 @@
+package taurosbot //trading-bot
+
+import (
+	"testing"
+
+	"git.vmo.mx/Tauros/tradingbot/exchange"
+)
+
+func TestAdoptOrders(t *testing.T) {
+	paper, _ := startPaper()
+	resetBots(unlockedVenue{paper, t})
+	buy, err := addOrder(0, "", dec("0.5"), "buy", dec("1900"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	orphan, err := addOrder(3, "", dec("0.5"), "sell", dec("2100"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	owned := adoptOrders([]bot{{Side: "buy"}, {Side: "sell"}})
+	if len(owned) != 1 || owned[0] != buy {
+		t.Errorf("adoptOrders = %+v, want bot 0 taking over #%s", owned, buy)
+	}
+	if tracked := trackedOrders(); len(tracked) != 1 || tracked[buy].Bot != 0 {
+		t.Errorf("tracked orders = %+v, want only #%s", tracked, buy)
+	}
+	if o, _ := paper.GetOrder(orphan); o.State != exchange.OrderCancelled {
+		t.Errorf("order #%s of a missing bot is %s, want it cancelled", orphan, o.State)
+	}
+}
@@ -0,0 +1,114 @@
+package taurosbot //trading-bot
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"runtime/debug"
+	"time"
+
+	"git.vmo.mx/Tauros/tradingbot/exchange"
+	log "github.com/sirupsen/logrus"
+)
+
+// maxBackoff - longest wait between failed bot cycles, unless the bot interval is longer
+var maxBackoff = time.Minute
+
+// maxBotRestarts - panics in a row after which the process cancels all the orders and exits
+var maxBotRestarts = 5
+
+// healthyRun - a bot that runs this long without panicking starts counting panics from zero
+var healthyRun = 10 * time.Minute
+
+func maxFailures() int {
+	if bots.MaxFailures > 0 {
+		return bots.MaxFailures
+	}
+	return 5
+}
+
+// botInterval - random wait before the next cycle of b, doubled for each failed cycle in a row
+func botInterval(b bot, failures int) time.Duration {
+	d := time.Duration(b.MinInterval+rand.Intn(b.MaxInterval-b.MinInterval)) * time.Millisecond
+	if failures == 0 || d >= maxBackoff {
+		return d
+	}
+	if failures > 16 {
+		failures = 16
+	}
+	if backoff := d << uint(failures); backoff < maxBackoff {
+		return backoff
+	}
+	return maxBackoff
+}
+
+// pullBotOrders cancels the orders of bot id, without holding the myOrders lock while cancelling
+func pullBotOrders(id int) {
+	myOrders.RLock()
+	var ids []exchange.OrderID
+	for orderID, o := range myOrders.orders {
+		if o.Bot == id {
+			ids = append(ids, orderID)
+		}
+	}
+	myOrders.RUnlock()
+	cancelOrders(ids)
+}
+
+// botOrder - an order of bot id, empty if it has none
+func botOrder(id int) exchange.OrderID {
+	myOrders.RLock()
+	defer myOrders.RUnlock()
+	for orderID, o := range myOrders.orders {
+		if o.Bot == id {
+			return orderID
+		}
+	}
+	return ""
+}
+
+// superviseBot runs bot id until ctx is done and restarts it when it panics, taking over its order.
+// After maxBotRestarts panics in a row the process shuts down
+func superviseBot(ctx context.Context, id int, b bot, orderID exchange.OrderID) {
+	defer wg.Done()
+	panics := 0
+	for {
+		start := time.Now()
+		if !runSupervised(ctx, id, b, orderID) || ctx.Err() != nil {
+			return
+		}
+		if time.Since(start) > healthyRun {
+			panics = 0
+		}
+		panics++
+		if panics >= maxBotRestarts {
+			requestExit(fmt.Sprintf("%s bot %d panicked %d times in a row", b.Side, id, panics))
+			return
+		}
+		delay := restartDelay(panics)
+		log.Warnf("Restarting %s bot %d in %s", b.Side, id, delay)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return
+		}
+		orderID = botOrder(id)
+	}
+}
+
+// restartDelay - wait before restarting a bot after its panics in a row
+func restartDelay(panics int) time.Duration {
+	return time.Duration(panics) * 5 * time.Second
+}
+
+// runSupervised runs the bot until it stops, true if it panicked
+func runSupervised(ctx context.Context, id int, b bot, orderID exchange.OrderID) (panicked bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("%s bot %d panicked: %v\n%s", b.Side, id, r, debug.Stack())
+			panicked = true
+		}
+	}()
+	runBot(ctx, id, b, orderID)
+	return false
+}
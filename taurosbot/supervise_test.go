@@ -0,0 +1,23 @@
+package taurosbot //trading-bot
+
+import "testing"
+
+func TestPullBotOrders(t *testing.T) {
+	paper, _ := startPaper()
+	resetBots(unlockedVenue{paper, t})
+	if _, err := addOrder(0, "", dec("0.5"), "buy", dec("1900")); err != nil {
+		t.Fatal(err)
+	}
+	sell, err := addOrder(1, "", dec("0.5"), "sell", dec("2100"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pullBotOrders(0)
+	if tracked := trackedOrders(); len(tracked) != 1 || tracked[sell].Bot != 1 {
+		t.Errorf("tracked orders = %+v, want only the sell #%s of bot 1", tracked, sell)
+	}
+	if open, _ := paper.OpenOrders("btc-mxn"); len(open) != 1 || open[0].ID != sell {
+		t.Errorf("open orders = %+v, want only the sell #%s", open, sell)
+	}
+}
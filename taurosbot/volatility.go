@@ -0,0 +1,95 @@
+package taurosbot //trading-bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	pb "git.vmo.mx/Tauros/tradingbot/proto"
+	"github.com/shopspring/decimal"
+	log "github.com/sirupsen/logrus"
+)
+
+// spreadModel - optional volatility adaptive spread, the spread is Multiplier times the realised
+// volatility of the Coinbase trades over the last Window candles, bounded by MinSpread and MaxSpread
+type spreadModel struct {
+	Interval   string          //candles used: "1s", "1m" or "5m"
+	Window     int             //number of candles
+	Multiplier decimal.Decimal //spread per unit of realised volatility
+	MinSpread  decimal.Decimal
+	MaxSpread  decimal.Decimal
+}
+
+var getCandles pb.TradesServiceClient
+
+// checkSpreadModel validates the spread model of the bots configuration
+func checkSpreadModel(m *spreadModel) error {
+	switch m.Interval {
+	case "1s", "1m", "5m":
+	default:
+		return fmt.Errorf("SpreadModel Interval must be 1s, 1m or 5m: %s", m.Interval)
+	}
+	if m.Window < 2 {
+		return errors.New("SpreadModel Window must be at least 2 candles")
+	}
+	if m.Multiplier.Sign() <= 0 {
+		return errors.New("SpreadModel Multiplier must be positive")
+	}
+	if m.MinSpread.Sign() < 0 || m.MaxSpread.LessThan(m.MinSpread) {
+		return fmt.Errorf("SpreadModel bounds are wrong: min %s max %s", m.MinSpread, m.MaxSpread)
+	}
+	return nil
+}
+
+// effectiveSpread - spread of the quotes, the configured Spread if there is no spread model or
+// the volatility cannot be computed
+func effectiveSpread() decimal.Decimal {
+	m := bots.SpreadModel
+	if m == nil {
+		return bots.Spread
+	}
+	vol, err := realisedVolatility(m.Interval, m.Window)
+	if err != nil {
+		log.Warnf("Unable to compute volatility, using Spread %s: %v", bots.Spread, err)
+		return bots.Spread
+	}
+	spread := decimal.Min(decimal.Max(vol.Mul(m.Multiplier), m.MinSpread), m.MaxSpread)
+	log.Debugf("Realised volatility %s over %d %s candles, spread %s", vol, m.Window, m.Interval, spread)
+	return spread
+}
+
+// realisedVolatility - square root of the sum of the squared log returns of the candle closes
+func realisedVolatility(interval string, window int) (decimal.Decimal, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), sourceTimeout)
+	defer cancel()
+	res, err := getCandles.GetCandles(ctx, &pb.CandlesRequest{
+		Market:   gdaxMarket,
+		Interval: interval,
+		Limit:    int32(window + 1),
+	})
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("realisedVolatility-> %v", err)
+	}
+	if len(res.Candles) < 2 {
+		return decimal.Zero, fmt.Errorf("only %d %s candles", len(res.Candles), interval)
+	}
+	var sum float64
+	var prev float64
+	for i, c := range res.Candles {
+		d, err := decimal.NewFromString(c.Close)
+		if err != nil {
+			return decimal.Zero, fmt.Errorf("bad candle close %s: %v", c.Close, err)
+		}
+		price, _ := d.Float64()
+		if price <= 0 {
+			return decimal.Zero, fmt.Errorf("bad candle close %s", c.Close)
+		}
+		if i > 0 {
+			r := math.Log(price / prev)
+			sum += r * r
+		}
+		prev = price
+	}
+	return decimal.NewFromFloat(math.Sqrt(sum)), nil
+}
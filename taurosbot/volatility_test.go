@@ -0,0 +1,38 @@
+package taurosbot //trading-bot
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "git.vmo.mx/Tauros/tradingbot/proto"
+	"google.golang.org/grpc"
+)
+
+// stuckCandles - gdax service that never answers the candles request
+type stuckCandles struct {
+	pb.TradesServiceClient
+}
+
+func (stuckCandles) GetCandles(ctx context.Context, in *pb.CandlesRequest, opts ...grpc.CallOption) (*pb.Candles, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestRealisedVolatilityTimeout(t *testing.T) {
+	defer func(c pb.TradesServiceClient, d time.Duration) { getCandles, sourceTimeout = c, d }(getCandles, sourceTimeout)
+	getCandles, sourceTimeout = stuckCandles{}, 50*time.Millisecond
+	done := make(chan error, 1)
+	go func() {
+		_, err := realisedVolatility("1m", 10)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Errorf("realisedVolatility of a stuck gdax service did not fail")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("realisedVolatility hangs on a stuck gdax service")
+	}
+}
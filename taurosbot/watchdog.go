@@ -0,0 +1,99 @@
+package taurosbot //trading-bot
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"git.vmo.mx/Tauros/tradingbot/exchange"
+	tau "git.vmo.mx/Tauros/tradingbot/taurosapi"
+	log "github.com/sirupsen/logrus"
+)
+
+// Tauros has no cancel on disconnect or dead man's switch endpoint, orders stay open until closed.
+// The watchdog cancels them when the bots stop heartbeating, with its own api token and without
+// taking any lock of the bots, so a stuck bot or a deadlocked mutex does not stop it
+
+// heartbeats - last time each bot finished a cycle
+var heartbeats struct {
+	sync.Mutex
+	last map[int]time.Time
+}
+
+// heartbeat records that bot id is alive
+func heartbeat(id int) {
+	heartbeats.Lock()
+	if heartbeats.last == nil {
+		heartbeats.last = make(map[int]time.Time)
+	}
+	heartbeats.last[id] = time.Now()
+	heartbeats.Unlock()
+}
+
+// staleBots - bots without a heartbeat for longer than timeout
+func staleBots(timeout time.Duration) []int {
+	heartbeats.Lock()
+	defer heartbeats.Unlock()
+	var stale []int
+	for id, t := range heartbeats.last {
+		if time.Since(t) > timeout {
+			stale = append(stale, id)
+		}
+	}
+	return stale
+}
+
+// checkWatchdog validates the watchdog timeout against the longest wait between bot cycles plus the
+// longest wait before restarting a bot that panicked, the bots do not heartbeat meanwhile
+func checkWatchdog(timeout time.Duration) error {
+	longest := maxBackoff
+	for _, b := range bots.Bots {
+		if d := time.Duration(b.MaxInterval) * time.Millisecond; d > longest {
+			longest = d
+		}
+	}
+	restart := restartDelay(maxBotRestarts - 1)
+	if timeout <= longest+restart {
+		return fmt.Errorf("WatchdogTimeout %s must be longer than the longest wait between bot cycles %s plus the longest bot restart delay %s", timeout, longest, restart)
+	}
+	return nil
+}
+
+// runWatchdog cancels all the market orders with token when a bot has not heartbeated for timeout,
+// again every timeout while it stays silent, until ctx is done
+func runWatchdog(ctx context.Context, timeout time.Duration, token string) {
+	defer wg.Done()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	var fired time.Time
+	for {
+		select {
+		case <-ticker.C:
+			stale := staleBots(timeout)
+			if len(stale) == 0 {
+				if !fired.IsZero() {
+					log.Warn("Watchdog: all the bots are heartbeating again")
+					fired = time.Time{}
+				}
+				continue
+			}
+			if time.Since(fired) < timeout {
+				continue
+			}
+			fired = time.Now()
+			log.Errorf("Watchdog: bots %v have not heartbeated for %s, cancelling all %s orders", stale, timeout, tauMarket)
+			results, err := tau.CancelOrdersWithToken(token, tau.CancelFilter{Market: tauMarket})
+			if err == nil {
+				err = exchange.CancelErrors(exchange.TauCancelResults(results))
+			}
+			if err != nil {
+				log.Errorf("Watchdog: unable to cancel %s orders: %v", tauMarket, err)
+			} else {
+				log.Errorf("Watchdog: cancelled %d %s orders", len(results), tauMarket)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package taurosbot //trading-bot
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCheckWatchdog(t *testing.T) {
+	defer func(bs []bot) { bots.Bots = bs }(bots.Bots)
+	tests := []struct {
+		name        string
+		maxInterval int //milliseconds
+		timeout     time.Duration
+		ok          bool
+	}{
+		{"longer than the backoff and the restart delay", 10000, 81 * time.Second, true},
+		{"within the restart delay after the backoff", 10000, 70 * time.Second, false},
+		{"the backoff plus the restart delay", 10000, 80 * time.Second, false},
+		{"longer than a long interval and the restart delay", 120000, 141 * time.Second, true},
+		{"within the restart delay after a long interval", 120000, 130 * time.Second, false},
+	}
+	for _, tt := range tests {
+		bots.Bots = []bot{{Side: "buy", MinInterval: 1000, MaxInterval: tt.maxInterval}}
+		if err := checkWatchdog(tt.timeout); (err == nil) != tt.ok {
+			t.Errorf("%s: checkWatchdog(%s) = %v, want ok %t", tt.name, tt.timeout, err, tt.ok)
+		}
+	}
+}
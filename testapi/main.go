@@ -6,7 +6,9 @@ import (
 	//"os/signal"
 	//"syscall"
 	"context"
+	"flag"
 
+	"git.vmo.mx/Tauros/tradingbot/grpcconf"
 	pb "git.vmo.mx/Tauros/tradingbot/proto"
 	"google.golang.org/grpc"
 )
@@ -23,10 +25,18 @@ func getBalances(market string) {
 }
 
 func main() {
-	var err error
-	grpcConn, err = grpc.Dial("localhost:2224", grpc.WithInsecure())
+	addr := flag.String("bal", grpcconf.Env("TB_BAL_ADDR", "localhost:2224"), "address of the balances service, env TB_BAL_ADDR")
+	var tlsConf grpcconf.TLS
+	tlsConf.Flags(flag.CommandLine, "grpc-", "TB_GRPC")
+	token := flag.String("bal-token", grpcconf.Env("TB_BAL_TOKEN", ""), "auth token of the balances service, env TB_BAL_TOKEN")
+	flag.Parse()
+	opts, err := grpcconf.DialOptions(tlsConf, *token)
 	if err != nil {
-		log.Fatalf("Unable to connect to GDAX grpc service at localhost:2224")
+		log.Fatalf("Invalid TLS configuration: %v", err)
+	}
+	grpcConn, err = grpc.Dial(*addr, opts...)
+	if err != nil {
+		log.Fatalf("Unable to connect to balances grpc service at %s: %v", *addr, err)
 	}
 	defer grpcConn.Close()
 	getTauBalances = pb.NewBalancesServiceClient(grpcConn)